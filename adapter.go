@@ -0,0 +1,27 @@
+package jsonpath
+
+import (
+	"reflect"
+
+	"github.com/xianlianghe0123/jsonpath/internal/ast"
+)
+
+// Adapter lets paths traverse custom containers, see RegisterAdapter. Index is only called
+// with a non-negative index, and must return false if it is past the end of the array.
+type Adapter = ast.Adapter
+
+// Kind tells an Adapter's container shape for a value.
+type Kind = ast.Kind
+
+const (
+	KindScalar = ast.KindScalar
+	KindObject = ast.KindObject
+	KindArray  = ast.KindArray
+)
+
+// RegisterAdapter makes `.name`, `[i]`, `[start:end]`, `*` and `..` traverse values of type t through a.
+// If t is an interface type, a applies to every type implementing it that has no adapter of its own.
+// Adapters take precedence over reflection and over WithMarshalers.
+func RegisterAdapter(t reflect.Type, a Adapter) {
+	ast.RegisterAdapter(t, a)
+}
@@ -0,0 +1,165 @@
+package jsonpath
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// testSyncMap and testList are traversed by the test adapters, so that registering them does
+// not change how the other tests see *sync.Map and *list.List.
+type testSyncMap struct{ sync.Map }
+
+type testList struct{ list.List }
+
+var registerTestAdapters sync.Once
+
+func registerAdapters() {
+	registerTestAdapters.Do(func() {
+		RegisterAdapter(reflect.TypeOf(&testSyncMap{}), syncMapAdapter{})
+		RegisterAdapter(reflect.TypeOf(&testList{}), listAdapter{})
+	})
+}
+
+type syncMapAdapter struct{}
+
+func (syncMapAdapter) Kind(v interface{}) Kind {
+	return KindObject
+}
+
+func (syncMapAdapter) Field(v interface{}, name string) (interface{}, bool) {
+	return v.(*testSyncMap).Load(name)
+}
+
+func (syncMapAdapter) Index(v interface{}, i int) (interface{}, bool) {
+	return nil, false
+}
+
+func (a syncMapAdapter) Len(v interface{}) int {
+	return len(a.Keys(v))
+}
+
+func (syncMapAdapter) Keys(v interface{}) []string {
+	keys := make([]string, 0)
+	v.(*testSyncMap).Range(func(key, _ interface{}) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+func (a syncMapAdapter) Range(v interface{}, fn func(key interface{}, value interface{}) bool) {
+	for _, k := range a.Keys(v) {
+		value, _ := a.Field(v, k)
+		if !fn(k, value) {
+			return
+		}
+	}
+}
+
+type listAdapter struct{}
+
+func (listAdapter) Kind(v interface{}) Kind {
+	return KindArray
+}
+
+func (listAdapter) Field(v interface{}, name string) (interface{}, bool) {
+	return nil, false
+}
+
+func (listAdapter) Index(v interface{}, i int) (interface{}, bool) {
+	if i < 0 {
+		panic("Index called with a negative index")
+	}
+	e := v.(*testList).Front()
+	for ; e != nil && i > 0; i-- {
+		e = e.Next()
+	}
+	if e == nil {
+		return nil, false
+	}
+	return e.Value, true
+}
+
+func (listAdapter) Len(v interface{}) int {
+	return v.(*testList).Len()
+}
+
+func (listAdapter) Keys(v interface{}) []string {
+	return nil
+}
+
+func (listAdapter) Range(v interface{}, fn func(key interface{}, value interface{}) bool) {
+	i := 0
+	for e := v.(*testList).Front(); e != nil; e = e.Next() {
+		if !fn(i, e.Value) {
+			return
+		}
+		i++
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	registerAdapters()
+
+	l := &testList{}
+	l.PushBack(map[string]interface{}{"name": "a"})
+	l.PushBack(&Bicycle{Color: "red", Price: 1})
+	l.PushBack("c")
+	m := &testSyncMap{}
+	m.Store("items", l)
+	m.Store("count", 3)
+	doc := map[string]interface{}{"root": m}
+
+	cases := []struct {
+		jsonPath    string
+		expectation string
+	}{
+		{`$.root.count`, `3`},
+		{`$.root.items[0].name`, `"a"`},
+		{`$.root.items[-1]`, `"c"`},
+		{`$.root.items[1:].color`, `["red"]`},
+		{`$.root.items[*].price`, `[1]`},
+		{`$.root.*`, `[3,[{"name":"a"},{"color":"red","price":1},"c"]]`},
+		{`$..name`, `["a"]`},
+		{`$.root['count','missing']`, `[3]`},
+	}
+	for _, c := range cases {
+		d, err := Get(c.jsonPath, doc)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			continue
+		}
+		b, _ := json.Marshal(adapted(d))
+		if string(b) != c.expectation {
+			t.Errorf("Case %q, current:%s, expectation:%s", c.jsonPath, b, c.expectation)
+		}
+	}
+	for _, path := range []string{`$.root.items[-4]`, `$.root.items[3]`} {
+		if d, err := Get(path, doc); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Case %q expected ErrNotFound, current:%v, err:%v", path, d, err)
+		}
+	}
+}
+
+// adapted replaces lists in results by slices so they can be compared as JSON.
+func adapted(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *testList:
+		s := make([]interface{}, 0, v.Len())
+		for e := v.Front(); e != nil; e = e.Next() {
+			s = append(s, adapted(e.Value))
+		}
+		return s
+	case []interface{}:
+		for i := range v {
+			v[i] = adapted(v[i])
+		}
+	}
+	return v
+}
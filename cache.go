@@ -0,0 +1,162 @@
+package jsonpath
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache stores compiled paths for the package-level Get, GetBytes and GetString.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(jsonPath string) (*Compiled, bool)
+	Add(jsonPath string, c *Compiled)
+}
+
+type CacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+const (
+	DefaultCacheSize = 256
+	maxCacheAliases  = 16
+)
+
+var (
+	cacheMu      sync.RWMutex
+	currentCache Cache = NewLRUCache(DefaultCacheSize)
+)
+
+// SetCache replaces the cache used by the package-level functions. A nil cache disables caching.
+func SetCache(c Cache) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	currentCache = c
+}
+
+// SetCacheSize replaces the cache used by the package-level functions with an empty
+// LRUCache of the given size. A size <= 0 disables caching.
+func SetCacheSize(size int) {
+	if size <= 0 {
+		SetCache(nil)
+		return
+	}
+	SetCache(NewLRUCache(size))
+}
+
+// GetCacheStats returns the statistics of the cache used by the package-level functions
+// if it is an *LRUCache.
+func GetCacheStats() CacheStats {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	if l, ok := currentCache.(*LRUCache); ok {
+		return l.Stats()
+	}
+	return CacheStats{}
+}
+
+func compileCached(jsonPath string) (*Compiled, error) {
+	cacheMu.RLock()
+	cache := currentCache
+	cacheMu.RUnlock()
+	if cache == nil {
+		return Compile(jsonPath)
+	}
+	if c, ok := cache.Get(jsonPath); ok {
+		return c, nil
+	}
+	c, err := Compile(jsonPath)
+	if err != nil {
+		return nil, err
+	}
+	cache.Add(jsonPath, c)
+	return c, nil
+}
+
+// LRUCache is a Cache holding at most size compiled paths. Paths are keyed by their normalized
+// form, so equivalent spellings such as `$.a` and `$['a']` share one entry.
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	ll      *list.List
+	entries map[string]*list.Element
+	aliases map[string]*list.Element
+	hits    uint64
+	misses  uint64
+}
+
+type lruEntry struct {
+	key     string
+	c       *Compiled
+	aliases []string
+}
+
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+		aliases: make(map[string]*list.Element),
+	}
+}
+
+func (l *LRUCache) Get(jsonPath string) (*Compiled, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.aliases[jsonPath]
+	if !ok {
+		l.misses++
+		return nil, false
+	}
+	l.hits++
+	l.ll.MoveToFront(e)
+	return e.Value.(*lruEntry).c, true
+}
+
+func (l *LRUCache) Add(jsonPath string, c *Compiled) {
+	if l.size <= 0 {
+		return
+	}
+	key := c.String()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.aliases[jsonPath]; ok {
+		return
+	}
+	e, ok := l.entries[key]
+	if !ok {
+		e = l.ll.PushFront(&lruEntry{key: key, c: c})
+		l.entries[key] = e
+		for l.ll.Len() > l.size {
+			l.remove(l.ll.Back())
+		}
+	} else {
+		l.ll.MoveToFront(e)
+	}
+	entry := e.Value.(*lruEntry)
+	if len(entry.aliases) == maxCacheAliases {
+		delete(l.aliases, entry.aliases[0])
+		entry.aliases = entry.aliases[1:]
+	}
+	entry.aliases = append(entry.aliases, jsonPath)
+	l.aliases[jsonPath] = e
+}
+
+func (l *LRUCache) remove(e *list.Element) {
+	entry := l.ll.Remove(e).(*lruEntry)
+	delete(l.entries, entry.key)
+	for _, a := range entry.aliases {
+		delete(l.aliases, a)
+	}
+}
+
+func (l *LRUCache) Stats() CacheStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return CacheStats{
+		Hits:    l.hits,
+		Misses:  l.misses,
+		Entries: l.ll.Len(),
+	}
+}
@@ -0,0 +1,72 @@
+package jsonpath
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestLRUCache(t *testing.T) {
+	l := NewLRUCache(2)
+	for _, p := range []string{`$.a`, `$['a']`, `$["a"]`} {
+		if _, ok := l.Get(p); !ok {
+			l.Add(p, MustCompile(p))
+		}
+	}
+	if s := l.Stats(); s.Entries != 1 || s.Misses != 3 || s.Hits != 0 {
+		t.Errorf("equivalent paths should share an entry, current:%+v", s)
+	}
+	a, _ := l.Get(`$.a`)
+	b, _ := l.Get(`$['a']`)
+	if a == nil || a != b {
+		t.Errorf("equivalent paths should share a compiled path")
+	}
+
+	l.Add(`$.b`, MustCompile(`$.b`))
+	l.Get(`$.a`)
+	l.Add(`$.c`, MustCompile(`$.c`))
+	if _, ok := l.Get(`$.b`); ok {
+		t.Errorf("least recently used path should be evicted")
+	}
+	if _, ok := l.Get(`$['a']`); !ok {
+		t.Errorf("recently used path should not be evicted")
+	}
+	if s := l.Stats(); s.Entries != 2 || s.Hits != 4 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	l.Add(`$.a[1,2].b`, MustCompile(`$.a[1,2].b`))
+	if c, ok := l.Get(`$.a[1,2].c`); ok {
+		t.Errorf("different paths should not share an entry, current:%s", c)
+	}
+}
+
+func TestCompileCached(t *testing.T) {
+	defer SetCacheSize(DefaultCacheSize)
+
+	SetCacheSize(8)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := Get(fmt.Sprintf(`$.store.book[%d].author`, (i+j)%4), data); err != nil {
+					t.Errorf("err: %+v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if s := GetCacheStats(); s.Entries != 4 || s.Hits+s.Misses != 800 || s.Misses < 4 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	SetCacheSize(0)
+	if _, err := Get(`$.store`, data); err != nil {
+		t.Errorf("err: %+v", err)
+	}
+	if s := GetCacheStats(); s != (CacheStats{}) {
+		t.Errorf("disabled cache should have no stats, current:%+v", s)
+	}
+}
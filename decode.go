@@ -0,0 +1,149 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xianlianghe0123/jsonpath/internal/ast"
+)
+
+const bigFloatPrec = 256
+
+type orderedDecoder struct {
+	d        *json.Decoder
+	keyOrder map[uintptr][]string
+}
+
+func decodeOrdered(d *json.Decoder) (interface{}, map[uintptr][]string, error) {
+	od := &orderedDecoder{
+		d:        d,
+		keyOrder: make(map[uintptr][]string),
+	}
+	v, err := od.decode()
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, od.keyOrder, nil
+}
+
+func (od *orderedDecoder) decode() (interface{}, error) {
+	t, err := od.d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		return od.decodeObject()
+	case json.Delim('['):
+		return od.decodeArray()
+	}
+	return t, nil
+}
+
+func (od *orderedDecoder) decodeObject() (interface{}, error) {
+	m := make(map[string]interface{})
+	keys := make([]string, 0)
+	for od.d.More() {
+		t, err := od.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid object key %v", t)
+		}
+		v, err := od.decode()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m[key]; !ok {
+			keys = append(keys, key)
+		}
+		m[key] = v
+	}
+	if _, err := od.d.Token(); err != nil {
+		return nil, err
+	}
+	od.keyOrder[reflect.ValueOf(m).Pointer()] = keys
+	return m, nil
+}
+
+func (od *orderedDecoder) decodeArray() (interface{}, error) {
+	a := make([]interface{}, 0)
+	for od.d.More() {
+		v, err := od.decode()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	if _, err := od.d.Token(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// convertNumbers replaces the json.Number values of v decoded with UseNumber by the
+// representation of mode. Maps and slices are updated in place.
+func convertNumbers(v interface{}, mode ast.NumberMode) (interface{}, error) {
+	if mode == ast.NumberJSON {
+		return v, nil
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			n, err := convertNumbers(e, mode)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = n
+		}
+	case []interface{}:
+		for i, e := range t {
+			n, err := convertNumbers(e, mode)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = n
+		}
+	case json.Number:
+		return convertNumber(t, mode)
+	}
+	return v, nil
+}
+
+func convertNumber(n json.Number, mode ast.NumberMode) (interface{}, error) {
+	s := string(n)
+	integral := !strings.ContainsAny(s, ".eE")
+	switch mode {
+	case ast.NumberFloat64:
+	case ast.NumberInt64:
+		if integral {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case ast.NumberBig:
+		if integral {
+			if i, ok := new(big.Int).SetString(s, 10); ok {
+				return i, nil
+			}
+		}
+		f, _, err := big.ParseFloat(s, 10, bigFloatPrec, big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s: %w", s, err)
+		}
+		return f, nil
+	default:
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s: %w", s, err)
+	}
+	return f, nil
+}
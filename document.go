@@ -0,0 +1,387 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/xianlianghe0123/jsonpath/internal/ast"
+)
+
+type docKind uint8
+
+const (
+	docNull docKind = iota
+	docFalse
+	docTrue
+	docNumber
+	docString
+	docObject
+	docArray
+)
+
+// docNode is a value of a Document. Objects and arrays hold the range
+// [start, start+n) of Document.members or Document.elems. Objects of more than
+// sortedMembersMin members also hold the range [sorted, sorted+n) of Document.sorted.
+type docNode struct {
+	kind   docKind
+	sorted int32
+	text   string
+	start  int32
+	n      int32
+}
+
+// sortedMembersMin is the number of members from which an object is looked up by binary search.
+const sortedMembersMin = 8
+
+type docMember struct {
+	key  string
+	node int32
+}
+
+// Document is a parsed JSON document that can be queried by any number of paths.
+// It keeps the order of object keys and the exact text of numbers.
+// A Document is immutable and safe for concurrent use.
+type Document struct {
+	nodes   []docNode
+	members []docMember
+	elems   []int32
+	// sorted holds the positions of the members of large objects in their object, sorted by key.
+	sorted []int32
+}
+
+// docValue is a node of a Document as seen by the paths, traversed by docAdapter.
+type docValue struct {
+	d *Document
+	i int32
+}
+
+func init() {
+	ast.RegisterBuiltinAdapter(reflect.TypeOf(docValue{}), docAdapter{})
+}
+
+// ParseDocument parses the JSON document b. Duplicate keys keep their first position and last value.
+func ParseDocument(b []byte) (*Document, error) {
+	p := &docParser{b: b, d: &Document{}}
+	i, err := p.parse(skipSpace(b, 0), 0)
+	if err != nil {
+		return nil, err
+	}
+	if i = skipSpace(b, i); i < len(b) {
+		return nil, syntaxError(b, i, "after top-level value")
+	}
+	return p.d, nil
+}
+
+// GetDocument evaluates the path against doc. Objects and arrays in the results are
+// returned as map[string]interface{} and []interface{}, numbers as json.Number. The returned
+// objects are plain maps and do not keep the key order of doc, though the results of `*` and
+// `..` are in document order whatever WithMapOrder is set to. Use GetLocations or Stream to
+// read matched objects in their document order.
+func (c *Compiled) GetDocument(doc *Document) (interface{}, error) {
+	if len(doc.nodes) == 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+	d, err := c.Get(docValue{d: doc, i: 0})
+	if err != nil {
+		return nil, err
+	}
+	switch v := d.(type) {
+	case docValue:
+		return v.value(), nil
+	case []interface{}:
+		for i, e := range v {
+			if dv, ok := e.(docValue); ok {
+				v[i] = dv.value()
+			}
+		}
+	}
+	return d, nil
+}
+
+type docParser struct {
+	b       []byte
+	d       *Document
+	members []docMember
+	elems   []int32
+}
+
+func (p *docParser) parse(i int, depth int) (int, error) {
+	b := p.b
+	if i >= len(b) {
+		return 0, syntaxError(b, i, "looking for beginning of value")
+	}
+	node := int32(len(p.d.nodes))
+	p.d.nodes = append(p.d.nodes, docNode{})
+	switch c := b[i]; {
+	case c == '"':
+		end, escaped, err := scanString(b, i)
+		if err != nil {
+			return 0, err
+		}
+		s, err := unquote(b[i:end], escaped)
+		if err != nil {
+			return 0, err
+		}
+		p.d.nodes[node] = docNode{kind: docString, text: s}
+		return end, nil
+	case c == '{':
+		if depth >= maxNestingDepth {
+			return 0, fmt.Errorf("exceeded max depth at offset %d", i)
+		}
+		return p.parseObject(node, i, depth+1)
+	case c == '[':
+		if depth >= maxNestingDepth {
+			return 0, fmt.Errorf("exceeded max depth at offset %d", i)
+		}
+		return p.parseArray(node, i, depth+1)
+	case c == '-' || isDigit(c):
+		end, err := scanNumber(b, i)
+		if err != nil {
+			return 0, err
+		}
+		p.d.nodes[node] = docNode{kind: docNumber, text: string(b[i:end])}
+		return end, nil
+	case c == 't':
+		p.d.nodes[node] = docNode{kind: docTrue}
+		return scanLiteral(b, i, "true")
+	case c == 'f':
+		p.d.nodes[node] = docNode{kind: docFalse}
+		return scanLiteral(b, i, "false")
+	case c == 'n':
+		return scanLiteral(b, i, "null")
+	}
+	return 0, syntaxError(b, i, "looking for beginning of value")
+}
+
+func (p *docParser) parseObject(node int32, i int, depth int) (int, error) {
+	b := p.b
+	base := len(p.members)
+	var seen map[string]int
+	i = skipSpace(b, i+1)
+	if i < len(b) && b[i] == '}' {
+		i++
+	} else {
+		for {
+			if i >= len(b) || b[i] != '"' {
+				return 0, syntaxError(b, i, "looking for beginning of object key string")
+			}
+			end, escaped, err := scanString(b, i)
+			if err != nil {
+				return 0, err
+			}
+			key, err := unquote(b[i:end], escaped)
+			if err != nil {
+				return 0, err
+			}
+			i = skipSpace(b, end)
+			if i >= len(b) || b[i] != ':' {
+				return 0, syntaxError(b, i, "after object key")
+			}
+			elem := int32(len(p.d.nodes))
+			if i, err = p.parse(skipSpace(b, i+1), depth); err != nil {
+				return 0, err
+			}
+			if seen == nil && len(p.members)-base >= 8 {
+				seen = make(map[string]int)
+				for j := base; j < len(p.members); j++ {
+					seen[p.members[j].key] = j
+				}
+			}
+			if j, ok := p.lookup(seen, base, key); ok {
+				p.members[j].node = elem
+			} else {
+				if seen != nil {
+					seen[key] = len(p.members)
+				}
+				p.members = append(p.members, docMember{key: key, node: elem})
+			}
+			i = skipSpace(b, i)
+			if i < len(b) && b[i] == ',' {
+				i = skipSpace(b, i+1)
+				continue
+			}
+			if i < len(b) && b[i] == '}' {
+				i++
+				break
+			}
+			return 0, syntaxError(b, i, "after object key:value pair")
+		}
+	}
+	n := docNode{kind: docObject, start: int32(len(p.d.members)), n: int32(len(p.members) - base)}
+	p.d.members = append(p.d.members, p.members[base:]...)
+	p.members = p.members[:base]
+	if n.n > sortedMembersMin {
+		n.sorted = int32(len(p.d.sorted))
+		members := p.d.members[n.start:]
+		for j := int32(0); j < n.n; j++ {
+			p.d.sorted = append(p.d.sorted, j)
+		}
+		sorted := p.d.sorted[n.sorted:]
+		sort.Slice(sorted, func(a, b int) bool {
+			return members[sorted[a]].key < members[sorted[b]].key
+		})
+	}
+	p.d.nodes[node] = n
+	return i, nil
+}
+
+func (p *docParser) lookup(seen map[string]int, base int, key string) (int, bool) {
+	if seen != nil {
+		j, ok := seen[key]
+		return j, ok
+	}
+	for j := base; j < len(p.members); j++ {
+		if p.members[j].key == key {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+func (p *docParser) parseArray(node int32, i int, depth int) (int, error) {
+	b := p.b
+	base := len(p.elems)
+	i = skipSpace(b, i+1)
+	if i < len(b) && b[i] == ']' {
+		i++
+	} else {
+		for {
+			elem := int32(len(p.d.nodes))
+			var err error
+			if i, err = p.parse(i, depth); err != nil {
+				return 0, err
+			}
+			p.elems = append(p.elems, elem)
+			i = skipSpace(b, i)
+			if i < len(b) && b[i] == ',' {
+				i = skipSpace(b, i+1)
+				continue
+			}
+			if i < len(b) && b[i] == ']' {
+				i++
+				break
+			}
+			return 0, syntaxError(b, i, "after array element")
+		}
+	}
+	p.d.nodes[node] = docNode{kind: docArray, start: int32(len(p.d.elems)), n: int32(len(p.elems) - base)}
+	p.d.elems = append(p.d.elems, p.elems[base:]...)
+	p.elems = p.elems[:base]
+	return i, nil
+}
+
+func (v docValue) node() *docNode {
+	return &v.d.nodes[v.i]
+}
+
+func (v docValue) members() []docMember {
+	n := v.node()
+	return v.d.members[n.start : n.start+n.n]
+}
+
+func (v docValue) elems() []int32 {
+	n := v.node()
+	return v.d.elems[n.start : n.start+n.n]
+}
+
+// value returns v as the value encoding/json with UseNumber would decode.
+func (v docValue) value() interface{} {
+	n := v.node()
+	switch n.kind {
+	case docFalse:
+		return false
+	case docTrue:
+		return true
+	case docNumber:
+		return json.Number(n.text)
+	case docString:
+		return n.text
+	case docObject:
+		m := make(map[string]interface{}, n.n)
+		for _, e := range v.members() {
+			m[e.key] = docValue{d: v.d, i: e.node}.value()
+		}
+		return m
+	case docArray:
+		a := make([]interface{}, 0, n.n)
+		for _, e := range v.elems() {
+			a = append(a, docValue{d: v.d, i: e}.value())
+		}
+		return a
+	}
+	return nil
+}
+
+type docAdapter struct{}
+
+func (docAdapter) Kind(v interface{}) ast.Kind {
+	switch v.(docValue).node().kind {
+	case docObject:
+		return ast.KindObject
+	case docArray:
+		return ast.KindArray
+	}
+	return ast.KindScalar
+}
+
+func (docAdapter) Field(v interface{}, name string) (interface{}, bool) {
+	dv := v.(docValue)
+	members := dv.members()
+	if n := dv.node(); n.n > sortedMembersMin {
+		sorted := dv.d.sorted[n.sorted : n.sorted+n.n]
+		j := sort.Search(len(sorted), func(j int) bool {
+			return members[sorted[j]].key >= name
+		})
+		if j < len(sorted) && members[sorted[j]].key == name {
+			return docValue{d: dv.d, i: members[sorted[j]].node}, true
+		}
+		return nil, false
+	}
+	for _, e := range members {
+		if e.key == name {
+			return docValue{d: dv.d, i: e.node}, true
+		}
+	}
+	return nil, false
+}
+
+func (docAdapter) Index(v interface{}, i int) (interface{}, bool) {
+	dv := v.(docValue)
+	elems := dv.elems()
+	if i < 0 || i >= len(elems) {
+		return nil, false
+	}
+	return docValue{d: dv.d, i: elems[i]}, true
+}
+
+func (docAdapter) Len(v interface{}) int {
+	return int(v.(docValue).node().n)
+}
+
+func (docAdapter) Keys(v interface{}) []string {
+	members := v.(docValue).members()
+	keys := make([]string, 0, len(members))
+	for _, e := range members {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+func (docAdapter) Range(v interface{}, fn func(key interface{}, value interface{}) bool) {
+	dv := v.(docValue)
+	if dv.node().kind == docObject {
+		for _, e := range dv.members() {
+			if !fn(e.key, docValue{d: dv.d, i: e.node}) {
+				return
+			}
+		}
+		return
+	}
+	for i, e := range dv.elems() {
+		if !fn(i, docValue{d: dv.d, i: e}) {
+			return
+		}
+	}
+}
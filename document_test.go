@@ -0,0 +1,163 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	b, _ := json.Marshal(data)
+	doc, err := ParseDocument(b)
+	if err != nil {
+		t.Fatalf("parse err: %+v", err)
+	}
+	paths := []string{
+		`$`,
+		`$.*`,
+		`$.store.book[*].author`,
+		`$.store.book[*].['author',"price"]`,
+		`$..author`,
+		`$.store.*`,
+		`$..price`,
+		`$..*`,
+		`$.store.book[-1]`,
+		`$.store.book[1:3]`,
+		`$.store.book[::-2].title`,
+		`$.store.book[0,2].isbn`,
+		`$.store.bicycle['color','size']`,
+	}
+	for _, p := range paths {
+		c := MustCompile(p, WithMapOrder(MapOrderDocument))
+		e, err := c.GetBytes(b)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", p, err)
+			continue
+		}
+		d, err := c.GetDocument(doc)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", p, err)
+			continue
+		}
+		if !reflect.DeepEqual(d, e) {
+			t.Errorf("Case %q, current:%v, expectation:%v", p, d, e)
+		}
+	}
+	if d, err := MustCompile(`$.store`, WithMapOrder(MapOrderDocument)).GetDocument(doc); err != nil {
+		t.Errorf("object err: %+v", err)
+	} else if _, ok := d.(map[string]interface{}); !ok {
+		t.Errorf("objects should be returned as maps, current:%T", d)
+	}
+	if _, err := MustCompile(`$.store.car`).GetDocument(doc); err == nil {
+		t.Errorf("missing member expected error")
+	}
+
+	doc, err = ParseDocument([]byte(` {"z":1.50,"a":[true,false,null,"é"],"z":-0e+1} `))
+	if err != nil {
+		t.Fatalf("parse err: %+v", err)
+	}
+	cases := []struct {
+		jsonPath    string
+		expectation string
+	}{
+		{`$.*`, `[-0e+1,[true,false,null,"é"]]`},
+		{`$.a[3]`, `"é"`},
+		{`$..*`, `[-0e+1,[true,false,null,"é"],true,false,null,"é"]`},
+	}
+	var wg sync.WaitGroup
+	for _, c := range cases {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(jsonPath, expectation string) {
+				defer wg.Done()
+				d, err := MustCompile(jsonPath).GetDocument(doc)
+				if err != nil {
+					t.Errorf("Case %q err: %+v", jsonPath, err)
+					return
+				}
+				b, _ := json.Marshal(d)
+				if string(b) != expectation {
+					t.Errorf("Case %q, current:%s, expectation:%s", jsonPath, b, expectation)
+				}
+			}(c.jsonPath, c.expectation)
+		}
+	}
+	wg.Wait()
+
+	wide := make(map[string]interface{})
+	var sb strings.Builder
+	sb.WriteString(`{"k0":"first"`)
+	for i := 99; i >= 0; i-- {
+		wide["k"+strconv.Itoa(i)] = float64(i)
+		fmt.Fprintf(&sb, `,"k%d":%d`, i, i)
+	}
+	sb.WriteString(`}`)
+	if doc, err = ParseDocument([]byte(sb.String())); err != nil {
+		t.Fatalf("parse err: %+v", err)
+	}
+	for key, value := range wide {
+		d, err := MustCompile(`$.` + key).GetDocument(doc)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", key, err)
+		} else if n, _ := d.(json.Number).Float64(); n != value {
+			t.Errorf("Case %q, current:%v, expectation:%v", key, d, value)
+		}
+	}
+	if _, err := MustCompile(`$.k100`).GetDocument(doc); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing member of a large object expected ErrNotFound, current:%v", err)
+	}
+	if d, err := MustCompile(`$.*`).GetDocument(doc); err != nil || len(d.([]interface{})) != 100 || d.([]interface{})[0] != json.Number("0") {
+		t.Errorf("large object members, current:%v, err:%v", d, err)
+	}
+
+	for _, s := range []string{``, `{`, `[1,]`, `{"a" 1}`, `01`, `1.`, `"\x"`, `tru`, `[1] 2`, `{"a":1,}`} {
+		if _, err := ParseDocument([]byte(s)); err == nil {
+			t.Errorf("Case %q expected error", s)
+		}
+	}
+}
+
+func BenchmarkGetDocument(b *testing.B) {
+	d, _ := json.Marshal(loadBigData(b))
+	doc, err := ParseDocument(d)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := MustCompile(`$.slaves[*].['id','pid']`)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.GetDocument(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetDocumentMember(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString(`{`)
+	for i := 0; i < 8000; i++ {
+		if i > 0 {
+			sb.WriteString(`,`)
+		}
+		fmt.Fprintf(&sb, `"key%d":%d`, i, i)
+	}
+	sb.WriteString(`}`)
+	doc, err := ParseDocument([]byte(sb.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := MustCompile(`$.key7999`)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.GetDocument(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/xianlianghe0123/jsonpath/internal/ast"
+)
+
+// edit replaces b[from:to] with text.
+type edit struct {
+	from int
+	to   int
+	text []byte
+}
+
+// SetBytes replaces the values of the JSON document b matched by the path with the JSON encoding
+// of value, and returns the edited document. Everything outside the matched values is kept
+// byte for byte. Values inside another matched value are left to it.
+//
+// If the path is made only of member names and indexes, and ends with a member missing from an
+// existing object, the member is added after the last one of the object, with the same comma,
+// indentation and colon spacing as it.
+func (c *Compiled) SetBytes(b []byte, value interface{}) ([]byte, error) {
+	text, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.matchRaw(b)
+	if errors.Is(err, ErrNotFound) {
+		if parent, name, ok := c.a.ParentField(); ok {
+			return c.insertMember(b, parent, name, text, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	edits := make([]edit, 0, len(values))
+	for _, v := range values {
+		edits = append(edits, edit{from: v.start, to: v.end, text: text})
+	}
+	return splice(b, edits), nil
+}
+
+// insertMember adds the member name with the value text to the object of b selected by parent.
+// It returns notFoundErr if parent does not select an object.
+func (c *Compiled) insertMember(b []byte, parent *ast.AST, name string, text []byte, notFoundErr error) ([]byte, error) {
+	values, err := (&Compiled{a: parent, opts: c.opts}).getRaw(&rawDoc{b: b})
+	if errors.Is(err, ErrNotFound) || err == nil && (len(values) != 1 || values[0].kind() != '{') {
+		return nil, notFoundErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	start := values[0].start
+	entries := make([]rawValue, 0)
+	doc := &rawDoc{b: b}
+	rawValue{d: doc, start: start}.rangeRaw(func(_ []byte, _ bool, elem rawValue) bool {
+		entries = append(entries, elem)
+		return true
+	})
+	if doc.err != nil {
+		return nil, doc.err
+	}
+	key, err := json.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		member := append(append(key, ':'), text...)
+		return splice(b, []edit{{from: start + 1, to: skipSpace(b, start+1), text: member}}), nil
+	}
+	last := entries[len(entries)-1]
+	keyEnd, _, err := scanString(b, last.entry)
+	if err != nil {
+		return nil, err
+	}
+	// The new member is preceded by what precedes the last member, with a comma if it is the first.
+	var member []byte
+	if len(entries) > 1 {
+		member = append(member, b[entries[len(entries)-2].end:last.entry]...)
+	} else {
+		member = append(append(member, ','), b[start+1:last.entry]...)
+	}
+	member = append(member, key...)
+	member = append(member, b[keyEnd:last.start]...)
+	member = append(member, text...)
+	return splice(b, []edit{{from: last.end, to: last.end, text: member}}), nil
+}
+
+// DeleteBytes removes the members and elements of the JSON document b matched by the path,
+// along with the commas separating them, and returns the edited document. Everything else is
+// kept byte for byte. The root can not be deleted.
+func (c *Compiled) DeleteBytes(b []byte) ([]byte, error) {
+	values, err := c.matchRaw(b)
+	if err != nil {
+		return nil, err
+	}
+	deleted := make(map[int]map[int]bool)
+	parents := make([]int, 0)
+	for _, v := range values {
+		if v.parent < 0 {
+			return nil, fmt.Errorf("can not delete the root")
+		}
+		if deleted[v.parent] == nil {
+			deleted[v.parent] = make(map[int]bool)
+			parents = append(parents, v.parent)
+		}
+		deleted[v.parent][v.start] = true
+	}
+	edits := make([]edit, 0, len(values))
+	for _, p := range parents {
+		entries := make([]rawValue, 0)
+		doc := &rawDoc{b: b}
+		rawValue{d: doc, start: p}.rangeRaw(func(_ []byte, _ bool, elem rawValue) bool {
+			entries = append(entries, elem)
+			return true
+		})
+		if doc.err != nil {
+			return nil, doc.err
+		}
+		edits = append(edits, deleteEntries(b, p, entries, deleted[p])...)
+	}
+	return splice(b, edits), nil
+}
+
+// deleteEntries returns the edits removing the entries of the container starting at b[start]
+// whose value starts are in deleted. A run of entries is removed with the space and comma
+// after it, or with the comma before it if it ends the container, so that the indentation
+// of the entries kept stays in place.
+func deleteEntries(b []byte, start int, entries []rawValue, deleted map[int]bool) []edit {
+	if len(deleted) == len(entries) {
+		last := entries[len(entries)-1]
+		return []edit{{from: start + 1, to: skipSpace(b, last.end)}}
+	}
+	edits := make([]edit, 0)
+	for i := 0; i < len(entries); {
+		if !deleted[entries[i].start] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(entries) && deleted[entries[j].start] {
+			j++
+		}
+		if j < len(entries) {
+			edits = append(edits, edit{from: entries[i].entry, to: entries[j].entry})
+		} else {
+			edits = append(edits, edit{from: entries[i-1].end, to: entries[j-1].end})
+		}
+		i = j
+	}
+	return edits
+}
+
+// matchRaw returns the values of b matched by the path sorted by start, leaving out the values
+// inside another matched value.
+func (c *Compiled) matchRaw(b []byte) ([]rawValue, error) {
+	values, err := c.getRaw(&rawDoc{b: b})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].start < values[j].start
+	})
+	result := make([]rawValue, 0, len(values))
+	for _, v := range values {
+		if n := len(result); n > 0 && v.start < result[n-1].end {
+			continue
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+// splice applies edits, which must not overlap, to a copy of b.
+func splice(b []byte, edits []edit) []byte {
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].from < edits[j].from
+	})
+	result := make([]byte, 0, len(b))
+	last := 0
+	for _, e := range edits {
+		result = append(result, b[last:e.from]...)
+		result = append(result, e.text...)
+		last = e.to
+	}
+	return append(result, b[last:]...)
+}
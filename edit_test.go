@@ -0,0 +1,171 @@
+package jsonpath
+
+import (
+	"testing"
+)
+
+const config = `{
+  "name": "svc",
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["a", "b", "c"],
+  "db": {"host": "localhost", "pool": 10}
+}`
+
+func TestSetBytes(t *testing.T) {
+	cases := []struct {
+		jsonPath    string
+		value       interface{}
+		hasErr      bool
+		expectation string
+	}{
+		{`$.port`, 9090, false, `{
+  "name": "svc",
+  "port": 9090,
+  "ratio": 1.50,
+  "tags": ["a", "b", "c"],
+  "db": {"host": "localhost", "pool": 10}
+}`},
+		{`$.tags[::2]`, "x", false, `{
+  "name": "svc",
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["x", "b", "x"],
+  "db": {"host": "localhost", "pool": 10}
+}`},
+		{`$..*`, nil, false, `{
+  "name": null,
+  "port": null,
+  "ratio": null,
+  "tags": null,
+  "db": null
+}`},
+		{`$`, map[string]int{"a": 1}, false, `{"a":1}`},
+		{`$.zz`, map[string]bool{"on": true}, false, `{
+  "name": "svc",
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["a", "b", "c"],
+  "db": {"host": "localhost", "pool": 10},
+  "zz": {"on":true}
+}`},
+		{`$.db.user`, "admin", false, `{
+  "name": "svc",
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["a", "b", "c"],
+  "db": {"host": "localhost", "pool": 10, "user": "admin"}
+}`},
+		{`$.tags.missing`, 1, true, ``},
+		{`$.db.missing.x`, 1, true, ``},
+	}
+	for _, c := range cases {
+		b, err := MustCompile(c.jsonPath).SetBytes([]byte(config), c.value)
+		if err != nil {
+			if !c.hasErr {
+				t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			}
+			continue
+		}
+		if c.hasErr {
+			t.Errorf("Case %q expected error, current:%s", c.jsonPath, b)
+			continue
+		}
+		if string(b) != c.expectation {
+			t.Errorf("Case %q, current:%s, expectation:%s", c.jsonPath, b, c.expectation)
+		}
+	}
+
+	inserts := []struct {
+		doc         string
+		jsonPath    string
+		expectation string
+	}{
+		{`{"a": { }}`, `$.a.b`, `{"a": {"b":1}}`},
+		{"{\n  \"x\": 0\n}", `$.b`, "{\n  \"x\": 0,\n  \"b\": 1\n}"},
+	}
+	for _, c := range inserts {
+		b, err := MustCompile(`$..b`).SetBytes([]byte(c.doc), 1)
+		if err != nil || string(b) != c.doc {
+			t.Errorf("Case %q, descendant paths should not insert, current:%s, err:%v", c.doc, b, err)
+		}
+		if b, err = MustCompile(c.jsonPath).SetBytes([]byte(c.doc), 1); err != nil {
+			t.Errorf("Case %q err: %+v", c.doc, err)
+		} else if string(b) != c.expectation {
+			t.Errorf("Case %q, current:%s, expectation:%s", c.doc, b, c.expectation)
+		}
+	}
+}
+
+func TestDeleteBytes(t *testing.T) {
+	cases := []struct {
+		jsonPath    string
+		hasErr      bool
+		expectation string
+	}{
+		{`$.name`, false, `{
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["a", "b", "c"],
+  "db": {"host": "localhost", "pool": 10}
+}`},
+		{`$.db`, false, `{
+  "name": "svc",
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["a", "b", "c"]
+}`},
+		{`$['port','ratio']`, false, `{
+  "name": "svc",
+  "tags": ["a", "b", "c"],
+  "db": {"host": "localhost", "pool": 10}
+}`},
+		{`$.tags[1:]`, false, `{
+  "name": "svc",
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["a"],
+  "db": {"host": "localhost", "pool": 10}
+}`},
+		{`$.tags[0,2]`, false, `{
+  "name": "svc",
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["b"],
+  "db": {"host": "localhost", "pool": 10}
+}`},
+		{`$..pool`, false, `{
+  "name": "svc",
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["a", "b", "c"],
+  "db": {"host": "localhost"}
+}`},
+		{`$..*`, false, `{}`},
+		{`$.db.*`, false, `{
+  "name": "svc",
+  "port": 8080,
+  "ratio": 1.50,
+  "tags": ["a", "b", "c"],
+  "db": {}
+}`},
+		{`$`, true, ``},
+		{`$.missing`, true, ``},
+	}
+	for _, c := range cases {
+		b, err := MustCompile(c.jsonPath).DeleteBytes([]byte(config))
+		if err != nil {
+			if !c.hasErr {
+				t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			}
+			continue
+		}
+		if c.hasErr {
+			t.Errorf("Case %q expected error, current:%s", c.jsonPath, b)
+			continue
+		}
+		if string(b) != c.expectation {
+			t.Errorf("Case %q, current:%s, expectation:%s", c.jsonPath, b, c.expectation)
+		}
+	}
+}
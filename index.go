@@ -0,0 +1,475 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xianlianghe0123/jsonpath/internal/ast"
+)
+
+// Index maps the member names, and optionally the scalar values, of a document to where they
+// are, so that paths starting with `..name` do not walk the whole document. It indexes the maps
+// with string keys and the slices of interface{}, as decoded by encoding/json; other values are
+// leaves. An Index is safe for concurrent use.
+type Index struct {
+	mu      sync.RWMutex
+	data    interface{}
+	values  bool
+	keys    map[string][]*indexEntry
+	byValue map[interface{}][]*indexEntry
+}
+
+// indexEntry is a member or element of the document. Entries are kept sorted by the order
+// `..` visits their parents in, see comparePaths.
+type indexEntry struct {
+	path  []pathElem
+	value interface{}
+}
+
+type IndexOption func(*Index)
+
+// WithValueIndex also indexes the members and elements holding strings, numbers, booleans and null by value.
+func WithValueIndex(enable bool) IndexOption {
+	return func(x *Index) {
+		x.values = enable
+	}
+}
+
+// BuildIndex indexes data. data must not be changed but through Set and Delete while the Index is used.
+func BuildIndex(data interface{}, opts ...IndexOption) *Index {
+	x := &Index{data: data}
+	for _, opt := range opts {
+		opt(x)
+	}
+	x.rebuild()
+	return x
+}
+
+func (x *Index) rebuild() {
+	x.keys = make(map[string][]*indexEntry)
+	x.byValue = nil
+	if x.values {
+		x.byValue = make(map[interface{}][]*indexEntry)
+	}
+	x.walk(x.data, nil, func(key string, e *indexEntry) {
+		x.keys[key] = append(x.keys[key], e)
+	}, func(v interface{}, e *indexEntry) {
+		x.byValue[v] = append(x.byValue[v], e)
+	})
+}
+
+// walk calls addKey for the members and addValue for the scalar members and elements under v,
+// at path, in the order `..` visits them.
+func (x *Index) walk(v interface{}, path []pathElem, addKey func(key string, e *indexEntry), addValue func(v interface{}, e *indexEntry)) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		entries := make([]*indexEntry, 0, len(keys))
+		for _, k := range keys {
+			e := &indexEntry{path: appendPath(path, pathElem{key: k, index: -1}), value: t[k]}
+			entries = append(entries, e)
+			addKey(k, e)
+		}
+		x.walkEntries(entries, addKey, addValue)
+	case []interface{}:
+		entries := make([]*indexEntry, 0, len(t))
+		for i, elem := range t {
+			entries = append(entries, &indexEntry{path: appendPath(path, pathElem{index: i}), value: elem})
+		}
+		x.walkEntries(entries, addKey, addValue)
+	}
+}
+
+func (x *Index) walkEntries(entries []*indexEntry, addKey func(key string, e *indexEntry), addValue func(v interface{}, e *indexEntry)) {
+	for _, e := range entries {
+		if k, ok := valueKey(e.value); ok && x.values {
+			addValue(k, e)
+		}
+	}
+	for _, e := range entries {
+		x.walk(e.value, e.path, addKey, addValue)
+	}
+}
+
+func appendPath(path []pathElem, e pathElem) []pathElem {
+	p := make([]pathElem, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, e)
+}
+
+// numberKey is the key numbers are indexed by value under, the same for all the Go types of
+// equal numbers, and not equal to a string.
+type numberKey string
+
+// valueKey returns the key v is indexed by value under, if it is a scalar.
+func valueKey(v interface{}) (interface{}, bool) {
+	switch t := v.(type) {
+	case nil, string, bool:
+		return t, true
+	case int:
+		return numberKey(strconv.FormatInt(int64(t), 10)), true
+	case int8:
+		return numberKey(strconv.FormatInt(int64(t), 10)), true
+	case int16:
+		return numberKey(strconv.FormatInt(int64(t), 10)), true
+	case int32:
+		return numberKey(strconv.FormatInt(int64(t), 10)), true
+	case int64:
+		return numberKey(strconv.FormatInt(t, 10)), true
+	case uint:
+		return numberKey(strconv.FormatUint(uint64(t), 10)), true
+	case uint8:
+		return numberKey(strconv.FormatUint(uint64(t), 10)), true
+	case uint16:
+		return numberKey(strconv.FormatUint(uint64(t), 10)), true
+	case uint32:
+		return numberKey(strconv.FormatUint(uint64(t), 10)), true
+	case uint64:
+		return numberKey(strconv.FormatUint(t, 10)), true
+	case float32:
+		return floatKey(float64(t)), true
+	case float64:
+		return floatKey(t), true
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return numberKey(strconv.FormatInt(i, 10)), true
+		}
+		if f, err := t.Float64(); err == nil {
+			return floatKey(f), true
+		}
+		return numberKey(t), true
+	}
+	return nil, false
+}
+
+// floatKey returns the numberKey of f, which is the one of an integer if f is integral.
+func floatKey(f float64) numberKey {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return numberKey(strconv.FormatInt(int64(f), 10))
+	}
+	return numberKey(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// comparePaths orders entries by the pre-order of their parents, members sorted by key
+// and elements by index, then by their own key or index.
+func comparePaths(a, b []pathElem) int {
+	if c := comparePrefix(a[:len(a)-1], b[:len(b)-1]); c != 0 {
+		return c
+	}
+	return compareElem(a[len(a)-1], b[len(b)-1])
+}
+
+func comparePrefix(a, b []pathElem) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareElem(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+func compareElem(a, b pathElem) int {
+	switch {
+	case a.index >= 0 && b.index >= 0:
+		return a.index - b.index
+	case a.index >= 0:
+		return -1
+	case b.index >= 0:
+		return 1
+	}
+	return strings.Compare(a.key, b.key)
+}
+
+func hasPrefix(path, prefix []pathElem) bool {
+	return len(path) >= len(prefix) && comparePrefix(path[:len(prefix)], prefix) == 0
+}
+
+// Lookup returns the normalized paths of the members named key, in the order `$..key` selects them.
+func (x *Index) Lookup(key string) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return entryPaths(x.keys[key])
+}
+
+// LookupValue returns the normalized paths of the members and elements equal to v, which must be a
+// string, number, boolean or nil. Numbers are compared by value whatever their Go type, so 1 finds
+// float64(1) and json.Number("1"). It returns nil if the Index was not built WithValueIndex.
+func (x *Index) LookupValue(v interface{}) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	k, ok := valueKey(v)
+	if !ok {
+		return nil
+	}
+	return entryPaths(x.byValue[k])
+}
+
+func entryPaths(entries []*indexEntry) []string {
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		paths = append(paths, formatPath(e.path))
+	}
+	return paths
+}
+
+// GetIndex evaluates the path against the document of x. Paths starting with `..name` look the
+// members named name up in x, other paths walk the document like Get. Maps are visited
+// sorted by key.
+func (c *Compiled) GetIndex(x *Index) (interface{}, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	name, rest, ok := c.a.DescendantField()
+	if !ok {
+		return c.Get(x.data)
+	}
+	entries := x.keys[name]
+	values := make([]interface{}, 0, len(entries))
+	for _, e := range entries {
+		values = append(values, e.value)
+	}
+	return rest.GetEach(ast.NewEnv(&c.opts), values)
+}
+
+// Set sets the member or element at path, which is made of member names and indexes only,
+// to value, and updates the index. Members are added if missing, elements must exist.
+func (x *Index) Set(path string, value interface{}) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	target, err := x.resolve(path)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		x.data = value
+		x.rebuild()
+		return nil
+	}
+	switch p := target.parent.(type) {
+	case map[string]interface{}:
+		p[target.elem.key] = value
+	case []interface{}:
+		if target.elem.index >= len(p) {
+			return fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		p[target.elem.index] = value
+	}
+	x.reindex(target.path(), false)
+	return nil
+}
+
+// Delete removes the member or element at path, which is made of member names and indexes only,
+// and updates the index. The root can not be deleted.
+func (x *Index) Delete(path string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	target, err := x.resolve(path)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("can not delete the root")
+	}
+	switch p := target.parent.(type) {
+	case map[string]interface{}:
+		if _, ok := p[target.elem.key]; !ok {
+			return fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		delete(p, target.elem.key)
+		x.reindex(target.path(), true)
+	case []interface{}:
+		if target.elem.index >= len(p) {
+			return fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		// Deleting an element moves the elements after it, so its whole array is reindexed.
+		a := append(p[:target.elem.index:target.elem.index], p[target.elem.index+1:]...)
+		x.replaceParent(target, a)
+		x.reindex(target.parentPath, false)
+	}
+	return nil
+}
+
+// indexTarget is the member or element elem of parent, a map or slice at parentPath in the
+// document, and grandparent holds parent.
+type indexTarget struct {
+	parent      interface{}
+	parentPath  []pathElem
+	grandparent interface{}
+	elem        pathElem
+}
+
+func (t *indexTarget) path() []pathElem {
+	return appendPath(t.parentPath, t.elem)
+}
+
+// resolve finds the member or element at path, or returns nil for the root.
+func (x *Index) resolve(path string) (*indexTarget, error) {
+	c, err := Compile(path)
+	if err != nil {
+		return nil, err
+	}
+	steps, ok := c.a.Steps()
+	if !ok {
+		return nil, fmt.Errorf("%s: path must be made of member names and indexes only", path)
+	}
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	t := &indexTarget{parent: x.data}
+	for i, step := range steps {
+		switch s := step.(type) {
+		case string:
+			if _, ok := t.parent.(map[string]interface{}); !ok {
+				return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+			}
+			t.elem = pathElem{key: s, index: -1}
+		case int:
+			a, ok := t.parent.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+			}
+			if s < 0 {
+				s += len(a)
+			}
+			if s < 0 {
+				return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+			}
+			t.elem = pathElem{index: s}
+		}
+		if i == len(steps)-1 {
+			break
+		}
+		next, ok := child(t.parent, t.elem)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		t.grandparent = t.parent
+		t.parentPath = append(t.parentPath, t.elem)
+		t.parent = next
+	}
+	return t, nil
+}
+
+func child(parent interface{}, e pathElem) (interface{}, bool) {
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		v, ok := p[e.key]
+		return v, ok
+	case []interface{}:
+		if e.index < len(p) {
+			return p[e.index], true
+		}
+	}
+	return nil, false
+}
+
+// replaceParent stores the slice a in place of the parent of t.
+func (x *Index) replaceParent(t *indexTarget, a []interface{}) {
+	if len(t.parentPath) == 0 {
+		x.data = a
+		return
+	}
+	last := t.parentPath[len(t.parentPath)-1]
+	switch g := t.grandparent.(type) {
+	case map[string]interface{}:
+		g[last.key] = a
+	case []interface{}:
+		g[last.index] = a
+	}
+}
+
+// reindex updates the entries of the value at path and under it. If deleted is set, the value
+// has been removed and its entries are only dropped.
+func (x *Index) reindex(path []pathElem, deleted bool) {
+	if len(path) == 0 {
+		x.rebuild()
+		return
+	}
+	remove := func(entries []*indexEntry) []*indexEntry {
+		// The entries under path are contiguous, since their parents have path as prefix.
+		lo := sort.Search(len(entries), func(i int) bool {
+			return comparePrefix(entries[i].path[:len(entries[i].path)-1], path) >= 0
+		})
+		hi := lo
+		for hi < len(entries) && hasPrefix(entries[hi].path[:len(entries[hi].path)-1], path) {
+			hi++
+		}
+		entries = append(entries[:lo:lo], entries[hi:]...)
+		i := sort.Search(len(entries), func(i int) bool {
+			return comparePaths(entries[i].path, path) >= 0
+		})
+		if i < len(entries) && comparePaths(entries[i].path, path) == 0 {
+			entries = append(entries[:i:i], entries[i+1:]...)
+		}
+		return entries
+	}
+	for k, entries := range x.keys {
+		if entries = remove(entries); len(entries) > 0 {
+			x.keys[k] = entries
+		} else {
+			delete(x.keys, k)
+		}
+	}
+	for k, entries := range x.byValue {
+		if entries = remove(entries); len(entries) > 0 {
+			x.byValue[k] = entries
+		} else {
+			delete(x.byValue, k)
+		}
+	}
+	if deleted {
+		return
+	}
+
+	parentPath := path[:len(path)-1]
+	var parent interface{} = x.data
+	for _, e := range parentPath {
+		parent, _ = child(parent, e)
+	}
+	v, _ := child(parent, path[len(path)-1])
+	e := &indexEntry{path: path, value: v}
+	keys := make(map[string][]*indexEntry)
+	var values map[interface{}][]*indexEntry
+	if x.values {
+		values = make(map[interface{}][]*indexEntry)
+	}
+	if elem := path[len(path)-1]; elem.index < 0 {
+		keys[elem.key] = append(keys[elem.key], e)
+	}
+	x.walkEntries([]*indexEntry{e}, func(key string, e *indexEntry) {
+		keys[key] = append(keys[key], e)
+	}, func(v interface{}, e *indexEntry) {
+		values[v] = append(values[v], e)
+	})
+	for k, added := range keys {
+		x.keys[k] = insertEntries(x.keys[k], added)
+	}
+	for k, added := range values {
+		x.byValue[k] = insertEntries(x.byValue[k], added)
+	}
+}
+
+// insertEntries merges the sorted entries added into the sorted entries.
+func insertEntries(entries, added []*indexEntry) []*indexEntry {
+	sort.SliceStable(added, func(i, j int) bool {
+		return comparePaths(added[i].path, added[j].path) < 0
+	})
+	result := make([]*indexEntry, 0, len(entries)+len(added))
+	i := 0
+	for _, e := range added {
+		for i < len(entries) && comparePaths(entries[i].path, e.path) < 0 {
+			result = append(result, entries[i])
+			i++
+		}
+		result = append(result, e)
+	}
+	return append(result, entries[i:]...)
+}
@@ -0,0 +1,134 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	b, _ := json.Marshal(data)
+	var doc interface{}
+	json.Unmarshal(b, &doc)
+	x := BuildIndex(doc, WithValueIndex(true))
+	paths := []string{`$..price`, `$..author`, `$..book[1:]`, `$..book[*].title`, `$..bicycle.color`, `$..missing`, `$.store.book[0]`, `$..*`}
+	check := func(step string) {
+		var fresh interface{}
+		x.mu.RLock()
+		fb, _ := json.Marshal(x.data)
+		x.mu.RUnlock()
+		json.Unmarshal(fb, &fresh)
+		y := BuildIndex(fresh, WithValueIndex(true))
+		for _, p := range paths {
+			e, errE := Get(p, fresh)
+			d, err := MustCompile(p).GetIndex(x)
+			if err != nil || errE != nil {
+				if err == nil || errE == nil {
+					t.Errorf("Step %s case %q err: %v, expected err: %v", step, p, err, errE)
+				}
+				continue
+			}
+			if !reflect.DeepEqual(d, e) {
+				t.Errorf("Step %s case %q, current:%v, expectation:%v", step, p, d, e)
+			}
+		}
+		for _, key := range []string{"price", "author", "isbn", "book", "color", "size"} {
+			if cur, e := x.Lookup(key), y.Lookup(key); !reflect.DeepEqual(cur, e) {
+				t.Errorf("Step %s key %q, current:%v, expectation:%v", step, key, cur, e)
+			}
+		}
+		for _, v := range []interface{}{"fiction", 8.95, "red", "blue", nil} {
+			if cur, e := x.LookupValue(v), y.LookupValue(v); !reflect.DeepEqual(cur, e) {
+				t.Errorf("Step %s value %v, current:%v, expectation:%v", step, v, cur, e)
+			}
+		}
+	}
+	check("build")
+	if cur := x.Lookup("price"); !reflect.DeepEqual(cur, []string{
+		`$['store']['bicycle']['price']`,
+		`$['store']['book'][0]['price']`,
+		`$['store']['book'][1]['price']`,
+		`$['store']['book'][2]['price']`,
+		`$['store']['book'][3]['price']`,
+	}) {
+		t.Errorf("Lookup price, current:%v", cur)
+	}
+
+	steps := []struct {
+		op    string
+		path  string
+		value interface{}
+	}{
+		{"set", `$.store.bicycle.color`, "blue"},
+		{"set", `$.store.bicycle.size`, map[string]interface{}{"price": 1.0, "color": "red"}},
+		{"set", `$.store.book[-1]`, map[string]interface{}{"author": "A", "price": 8.95}},
+		{"delete", `$.store.book[0]`, nil},
+		{"delete", `$.store.bicycle.price`, nil},
+		{"set", `$.store.book`, []interface{}{map[string]interface{}{"isbn": "x"}}},
+		{"delete", `$.store.bicycle`, nil},
+		{"set", `$`, map[string]interface{}{"price": 2.0}},
+	}
+	for _, s := range steps {
+		var err error
+		if s.op == "set" {
+			err = x.Set(s.path, s.value)
+		} else {
+			err = x.Delete(s.path)
+		}
+		if err != nil {
+			t.Errorf("Step %s %s err: %+v", s.op, s.path, err)
+			continue
+		}
+		check(s.op + " " + s.path)
+	}
+	for _, p := range []string{`$`, `$.missing`, `$..price`, `$[0]`} {
+		if err := x.Delete(p); err == nil {
+			t.Errorf("Delete %q expected error", p)
+		}
+	}
+
+	numbers := `{"a":1,"b":[2.5,1e0],"c":"1"}`
+	var floats, useNumber interface{}
+	json.Unmarshal([]byte(numbers), &floats)
+	d := json.NewDecoder(strings.NewReader(numbers))
+	d.UseNumber()
+	d.Decode(&useNumber)
+	for _, doc := range []interface{}{floats, useNumber} {
+		x := BuildIndex(doc, WithValueIndex(true))
+		for _, v := range []interface{}{1, int64(1), 1.0, json.Number("1.0"), uint8(1)} {
+			if cur := x.LookupValue(v); !reflect.DeepEqual(cur, []string{`$['a']`, `$['b'][1]`}) {
+				t.Errorf("Document %T value %T(%v), current:%v", doc, v, v, cur)
+			}
+		}
+		if cur := x.LookupValue(float32(2.5)); !reflect.DeepEqual(cur, []string{`$['b'][0]`}) {
+			t.Errorf("Document %T value 2.5, current:%v", doc, cur)
+		}
+		if cur := x.LookupValue("1"); !reflect.DeepEqual(cur, []string{`$['c']`}) {
+			t.Errorf("Document %T value \"1\", current:%v", doc, cur)
+		}
+	}
+}
+
+func BenchmarkGetIndex(b *testing.B) {
+	d := loadBigData(b)
+	c := MustCompile(`$..hostname`)
+	b.Run("walk", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := c.Get(d); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("index", func(b *testing.B) {
+		x := BuildIndex(d)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := c.GetIndex(x); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
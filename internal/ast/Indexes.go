@@ -17,18 +17,22 @@ func NewIndexes(nodes []Node) *Indexes {
 func (i *Indexes) String() string {
 	builder := strings.Builder{}
 	builder.WriteRune('[')
+	next := ""
 	for j, node := range i.nodes {
-		builder.WriteString(strings.SplitN(node.String(), "]", 2)[0][1:])
+		s := strings.SplitN(node.String(), "]", 2)
+		builder.WriteString(s[0][1:])
+		next = s[1]
 		if j < len(i.nodes)-1 {
 			builder.WriteRune(',')
 		}
 	}
 	builder.WriteRune(']')
+	builder.WriteString(next)
 	return builder.String()
 }
 
-func (i *Indexes) Get(data interface{}) (*Result, error) {
-	r, err := i.get(data)
+func (i *Indexes) Get(env *Env, data interface{}) (*Result, error) {
+	r, err := i.get(env, data)
 	if err != nil {
 		return nil, err
 	}
@@ -38,11 +42,14 @@ func (i *Indexes) Get(data interface{}) (*Result, error) {
 	}, nil
 }
 
-func (i *Indexes) get(data interface{}) ([]interface{}, error) {
+func (i *Indexes) get(env *Env, data interface{}) ([]interface{}, error) {
 	result := make([]interface{}, 0)
 	for _, n := range i.nodes {
-		r, err := n.Get(data)
+		r, err := n.Get(env, data)
 		if err != nil {
+			if env.Err() != nil {
+				return nil, err
+			}
 			continue
 		}
 		if r.multi {
@@ -0,0 +1,92 @@
+package ast
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+type Kind int
+
+const (
+	KindScalar Kind = iota
+	KindObject
+	KindArray
+)
+
+// Adapter lets the nodes traverse a type that is not a map, struct, slice or array.
+// Every method receives the value being traversed. Objects are accessed by Field, Keys and Range,
+// arrays by Index, Len and Range, where Range calls fn with string keys for objects and int indexes
+// for arrays until fn returns false. Index is only called with a non-negative i, and must return false
+// if i is not less than the length of the array.
+type Adapter interface {
+	Kind(v interface{}) Kind
+	Field(v interface{}, name string) (interface{}, bool)
+	Index(v interface{}, i int) (interface{}, bool)
+	Len(v interface{}) int
+	Keys(v interface{}) []string
+	Range(v interface{}, fn func(key interface{}, value interface{}) bool)
+}
+
+var (
+	// builtinAdapters are the adapters of the types of this module. They are only set by init
+	// functions, so they are looked up without locking, and do not count in adapterCount.
+	builtinAdapters   []interfaceAdapter
+	adapters          sync.Map // map[reflect.Type]Adapter
+	adapterCount      int32
+	interfaceMu       sync.RWMutex
+	interfaceAdapters []interfaceAdapter
+)
+
+type interfaceAdapter struct {
+	t reflect.Type
+	a Adapter
+}
+
+// RegisterAdapter registers a for values of type t. If t is an interface type,
+// a is used for every type implementing t that has no adapter of its own.
+func RegisterAdapter(t reflect.Type, a Adapter) {
+	if t.Kind() == reflect.Interface {
+		interfaceMu.Lock()
+		interfaceAdapters = append(interfaceAdapters, interfaceAdapter{t: t, a: a})
+		interfaceMu.Unlock()
+	} else {
+		adapters.Store(t, a)
+	}
+	atomic.AddInt32(&adapterCount, 1)
+}
+
+// RegisterBuiltinAdapter registers a for values of the concrete type t, apart from the adapters
+// registered by users. It must only be called by init functions.
+func RegisterBuiltinAdapter(t reflect.Type, a Adapter) {
+	builtinAdapters = append(builtinAdapters, interfaceAdapter{t: t, a: a})
+}
+
+func lookupAdapter(data interface{}) (Adapter, bool) {
+	if data == nil {
+		return nil, false
+	}
+	return lookupAdapterType(reflect.TypeOf(data))
+}
+
+func lookupAdapterType(t reflect.Type) (Adapter, bool) {
+	for _, ba := range builtinAdapters {
+		if ba.t == t {
+			return ba.a, true
+		}
+	}
+	if atomic.LoadInt32(&adapterCount) == 0 {
+		return nil, false
+	}
+	if a, ok := adapters.Load(t); ok {
+		return a.(Adapter), true
+	}
+	interfaceMu.RLock()
+	defer interfaceMu.RUnlock()
+	for _, ia := range interfaceAdapters {
+		if t.Implements(ia.t) {
+			return ia.a, true
+		}
+	}
+	return nil, false
+}
@@ -19,8 +19,8 @@ func (a *All) String() string {
 	return fmt.Sprintf("[*]%s", a.next.String())
 }
 
-func (a *All) Get(r interface{}) (*Result, error) {
-	r, err := a.get(r)
+func (a *All) Get(env *Env, data interface{}) (*Result, error) {
+	r, err := a.get(env, data)
 	if err != nil {
 		return nil, err
 	}
@@ -30,53 +30,135 @@ func (a *All) Get(r interface{}) (*Result, error) {
 	}, nil
 }
 
-func (a *All) get(data interface{}) ([]interface{}, error) {
+func (a *All) get(env *Env, data interface{}) ([]interface{}, error) {
+	if err := env.visit(); err != nil {
+		return nil, err
+	}
+	data, err := env.resolve(data)
+	if err != nil {
+		return nil, err
+	}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		return a.getObject(env, v)
+	case []interface{}:
+		return a.getSlice(env, v)
+	}
+	if ad, ok := lookupAdapter(data); ok {
+		return a.getAdapter(env, ad, data)
+	}
 	value := reflect.ValueOf(data)
 	for value.Kind() == reflect.Ptr {
 		if value.IsNil() {
-			return nil, fmt.Errorf("can not find * from nil")
+			return nil, notFound("can not find * from nil")
 		}
 		value = value.Elem()
 	}
 
 	switch value.Kind() {
 	case reflect.Map:
-		return a.getMap(value)
+		return a.getMap(env, value)
 	case reflect.Struct:
-		return a.getStruct(value)
+		return a.getStruct(env, value)
 	case reflect.Slice, reflect.Array:
-		return a.getArray(value)
+		return a.getArray(env, value)
 	default:
-		return nil, fmt.Errorf("unsupported find * from %s", value.Kind())
+		return nil, notFound("unsupported find * from %s", value.Kind())
 	}
 }
 
-func (a *All) getMap(value reflect.Value) ([]interface{}, error) {
+func (a *All) getMap(env *Env, value reflect.Value) ([]interface{}, error) {
 	result := make([]interface{}, 0, value.Len())
-	iter := value.MapRange()
-	for iter.Next() {
-		r, err := a.next.Get(iter.Value().Interface())
-		if err != nil {
-			continue
+	var err error
+	env.rangeMap(value, func(_, elem reflect.Value) bool {
+		r, e := a.next.Get(env, elem.Interface())
+		if e != nil {
+			err = env.Err()
+			return err == nil
 		}
 		if r.multi {
 			result = append(result, r.data.([]interface{})...)
 		} else {
 			result = append(result, r.data)
 		}
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
 	return result, nil
 }
 
-func (a *All) getStruct(value reflect.Value) ([]interface{}, error) {
-	result := make([]interface{}, 0, value.NumField())
-	for i := 0; i < value.NumField(); i++ {
-		_, omitempty := getFieldKey(value.Type().Field(i))
-		if omitempty && value.Field(i).IsZero() {
+func (a *All) getObject(env *Env, m map[string]interface{}) ([]interface{}, error) {
+	result := make([]interface{}, 0, len(m))
+	var err error
+	env.rangeObject(m, func(_ string, elem interface{}) bool {
+		r, e := a.next.Get(env, elem)
+		if e != nil {
+			err = env.Err()
+			return err == nil
+		}
+		if r.multi {
+			result = append(result, r.data.([]interface{})...)
+		} else {
+			result = append(result, r.data)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *All) getAdapter(env *Env, ad Adapter, data interface{}) ([]interface{}, error) {
+	switch ad.Kind(data) {
+	case KindObject:
+	case KindArray:
+		if ad.Len(data) == 0 {
+			return nil, notFound("empty array")
+		}
+	default:
+		return nil, notFound("unsupported find * from %T", data)
+	}
+	result := make([]interface{}, 0)
+	var err error
+	ad.Range(data, func(_ interface{}, elem interface{}) bool {
+		r, e := a.next.Get(env, elem)
+		if e != nil {
+			err = env.Err()
+			return err == nil
+		}
+		if r.multi {
+			result = append(result, r.data.([]interface{})...)
+		} else {
+			result = append(result, r.data)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *All) getStruct(env *Env, value reflect.Value) ([]interface{}, error) {
+	fields := env.typeFields(value.Type())
+	result := make([]interface{}, 0, len(fields.list))
+	for i := range fields.list {
+		f := &fields.list[i]
+		if !env.fieldVisible(f) {
+			continue
+		}
+		fv, ok := f.value(value)
+		if !ok {
 			continue
 		}
-		r, err := a.next.Get(value.Field(i).Interface())
+		r, err := a.next.Get(env, fv.Interface())
 		if err != nil {
+			if env.Err() != nil {
+				return nil, err
+			}
 			continue
 		}
 		if r.multi {
@@ -86,19 +168,44 @@ func (a *All) getStruct(value reflect.Value) ([]interface{}, error) {
 		}
 	}
 	if len(result) == 0 {
-		return nil, fmt.Errorf("empty struct")
+		return nil, notFound("empty struct")
+	}
+	return result, nil
+}
+
+func (a *All) getSlice(env *Env, s []interface{}) ([]interface{}, error) {
+	if len(s) == 0 {
+		return nil, notFound("empty array")
+	}
+	result := make([]interface{}, 0, len(s))
+	for _, elem := range s {
+		r, err := a.next.Get(env, elem)
+		if err != nil {
+			if env.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+		if r.multi {
+			result = append(result, r.data.([]interface{})...)
+		} else {
+			result = append(result, r.data)
+		}
 	}
 	return result, nil
 }
 
-func (a *All) getArray(value reflect.Value) ([]interface{}, error) {
+func (a *All) getArray(env *Env, value reflect.Value) ([]interface{}, error) {
 	if value.Len() == 0 {
-		return nil, fmt.Errorf("empty array")
+		return nil, notFound("empty array")
 	}
 	result := make([]interface{}, 0, value.Len())
 	for i := 0; i < value.Len(); i++ {
-		r, err := a.next.Get(value.Index(i).Interface())
+		r, err := a.next.Get(env, value.Index(i).Interface())
 		if err != nil {
+			if env.Err() != nil {
+				return nil, err
+			}
 			continue
 		}
 		if r.multi {
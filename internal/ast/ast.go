@@ -1,12 +1,35 @@
 package ast
 
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is matched by the errors reporting that a path selects nothing,
+// as opposed to selecting a null value.
+var ErrNotFound = errors.New("not found")
+
+type notFoundError string
+
+func (e notFoundError) Error() string {
+	return string(e)
+}
+
+func (e notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func notFound(format string, args ...interface{}) error {
+	return notFoundError(fmt.Sprintf(format, args...))
+}
+
 type Result struct {
 	data  interface{}
 	multi bool
 }
 
 type Node interface {
-	Get(interface{}) (*Result, error)
+	Get(*Env, interface{}) (*Result, error)
 	String() string
 }
 
@@ -27,13 +50,147 @@ func (a *AST) String() string {
 	return a.node.String()
 }
 
-func (a *AST) Get(data interface{}) (interface{}, error) {
+func (a *AST) Get(env *Env, data interface{}) (interface{}, error) {
 	if a.node == nil {
 		return data, nil
 	}
-	result, err := a.node.Get(data)
+	result, err := a.node.Get(env, data)
+	if env.Err() != nil {
+		return nil, env.Err()
+	}
 	if err != nil {
 		return nil, err
 	}
 	return result.data, nil
 }
+
+func (a *AST) Exists(env *Env, data interface{}) (bool, error) {
+	if a.node == nil {
+		return true, nil
+	}
+	result, err := a.node.Get(env, data)
+	if env.Err() != nil {
+		return false, env.Err()
+	}
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if result.multi {
+		return len(result.data.([]interface{})) > 0, nil
+	}
+	return true, nil
+}
+
+// Prefix splits the path into its leading member and index steps, each returned as a path
+// of its own, and the path of the segments after them.
+func (a *AST) Prefix() ([]*AST, *AST) {
+	steps := make([]*AST, 0)
+	n := a.node
+	for n != nil {
+		switch t := n.(type) {
+		case *Root:
+			n = t.next
+			continue
+		case *SingleField:
+			steps = append(steps, NewAST(NewSingleField(t.field, NewEnd())))
+			n = t.next
+			continue
+		case *Index:
+			steps = append(steps, NewAST(NewIndexField(t.index, NewEnd())))
+			n = t.next
+			continue
+		}
+		break
+	}
+	return steps, NewAST(n)
+}
+
+// Steps returns the member names and array indexes of a path made only of them.
+func (a *AST) Steps() ([]interface{}, bool) {
+	steps := make([]interface{}, 0)
+	for n := a.node; n != nil; {
+		switch t := n.(type) {
+		case *Root:
+			n = t.next
+		case *SingleField:
+			steps = append(steps, t.field)
+			n = t.next
+		case *Index:
+			steps = append(steps, t.index)
+			n = t.next
+		case End:
+			return steps, true
+		default:
+			return nil, false
+		}
+	}
+	return steps, true
+}
+
+// ParentField reports whether the path is made only of member names and indexes and ends with a
+// member name, and returns the path of the object holding that member and the name.
+func (a *AST) ParentField() (*AST, string, bool) {
+	steps, ok := a.Steps()
+	if !ok || len(steps) == 0 {
+		return nil, "", false
+	}
+	name, ok := steps[len(steps)-1].(string)
+	if !ok {
+		return nil, "", false
+	}
+	var n Node = NewEnd()
+	for i := len(steps) - 2; i >= 0; i-- {
+		switch s := steps[i].(type) {
+		case string:
+			n = NewSingleField(s, n)
+		case int:
+			n = NewIndexField(s, n)
+		}
+	}
+	return NewAST(NewRoot(n)), name, true
+}
+
+// DescendantField reports whether the path starts with `..name`, and returns name and the path after it.
+func (a *AST) DescendantField() (string, *AST, bool) {
+	n := a.node
+	if r, ok := n.(*Root); ok {
+		n = r.next
+	}
+	r, ok := n.(*Recursion)
+	if !ok {
+		return "", nil, false
+	}
+	f, ok := r.next.(*SingleField)
+	if !ok {
+		return "", nil, false
+	}
+	return f.field, NewAST(f.next), true
+}
+
+// GetEach evaluates the path against each of values, and returns all the results in order.
+// Values the path selects nothing of are skipped.
+func (a *AST) GetEach(env *Env, values []interface{}) ([]interface{}, error) {
+	result := make([]interface{}, 0)
+	for _, v := range values {
+		if a.node == nil {
+			result = append(result, v)
+			continue
+		}
+		r, err := a.node.Get(env, v)
+		if err != nil {
+			if env.Err() != nil {
+				return nil, env.Err()
+			}
+			continue
+		}
+		if r.multi {
+			result = append(result, r.data.([]interface{})...)
+		} else {
+			result = append(result, r.data)
+		}
+	}
+	return result, nil
+}
@@ -10,7 +10,14 @@ func (e End) String() string {
 	return ""
 }
 
-func (e End) Get(data interface{}) (*Result, error) {
+func (e End) Get(env *Env, data interface{}) (*Result, error) {
+	if err := env.addResult(); err != nil {
+		return nil, err
+	}
+	data, err := env.resolve(data)
+	if err != nil {
+		return nil, err
+	}
 	return &Result{
 		data:  data,
 		multi: false,
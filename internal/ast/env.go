@@ -0,0 +1,388 @@
+package ast
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type MapOrder int
+
+const (
+	MapOrderSorted MapOrder = iota
+	MapOrderRandom
+	MapOrderDocument
+)
+
+type CyclePolicy int
+
+const (
+	CycleReturnError CyclePolicy = iota
+	CycleSkip
+)
+
+type NumberMode int
+
+const (
+	NumberJSON NumberMode = iota
+	NumberFloat64
+	NumberInt64
+	NumberBig
+)
+
+type Options struct {
+	MapOrder         MapOrder
+	CyclePolicy      CyclePolicy
+	UseMarshalers    bool
+	Unwrap           bool
+	TagKeys          []string
+	CaseInsensitive  bool
+	FieldPolicy      func(sf reflect.StructField) bool
+	UnexportedFields bool
+	Numbers          NumberMode
+	// ParallelWorkers and ParallelThreshold make `..` evaluate the elements of arrays of at
+	// least ParallelThreshold elements with up to ParallelWorkers goroutines.
+	ParallelWorkers   int
+	ParallelThreshold int
+}
+
+type Env struct {
+	opts     *Options
+	tags     string
+	keyOrder map[uintptr][]string
+	ctx      context.Context
+	limits   Limits
+	visited  int
+	results  int
+	err      error
+	ancestor map[cycleKey]struct{}
+	forked   bool
+}
+
+type cycleKey struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+func NewEnv(opts *Options) *Env {
+	if opts == nil {
+		opts = &Options{}
+	}
+	tags := "json"
+	if len(opts.TagKeys) > 0 {
+		tags = strings.Join(opts.TagKeys, ",")
+	}
+	return &Env{
+		opts: opts,
+		tags: tags,
+	}
+}
+
+func (e *Env) typeFields(t reflect.Type) *structFields {
+	return cachedTypeFields(t, e.tags, e.opts.UnexportedFields)
+}
+
+func (e *Env) fieldVisible(f *field) bool {
+	return e.opts.FieldPolicy == nil || e.opts.FieldPolicy(f.sf)
+}
+
+// SetKeyOrder records the document order of the keys of decoded maps, indexed by map pointer.
+func (e *Env) SetKeyOrder(keyOrder map[uintptr][]string) {
+	e.keyOrder = keyOrder
+}
+
+func (e *Env) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+func (e *Env) SetLimits(limits Limits) {
+	e.limits = limits
+}
+
+// Err returns the error that aborted the evaluation, if any.
+func (e *Env) Err() error {
+	return e.err
+}
+
+func (e *Env) abort(err error) error {
+	if e.err == nil {
+		e.err = err
+	}
+	return e.err
+}
+
+func (e *Env) visit() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.visited++
+	if e.limits.MaxVisited > 0 && e.visited > e.limits.MaxVisited {
+		return e.abort(&LimitError{Kind: LimitVisited, Limit: e.limits.MaxVisited})
+	}
+	if e.ctx != nil && e.visited%64 == 1 {
+		if err := e.ctx.Err(); err != nil {
+			return e.abort(err)
+		}
+	}
+	return nil
+}
+
+func (e *Env) addResult() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.results++
+	if e.limits.MaxResults > 0 && e.results > e.limits.MaxResults {
+		return e.abort(&LimitError{Kind: LimitResults, Limit: e.limits.MaxResults})
+	}
+	return nil
+}
+
+// workers returns the number of goroutines to evaluate the elements of an array of length n with.
+// Envs forked for a parallel evaluation do not fork again.
+func (e *Env) workers(n int) int {
+	w := e.opts.ParallelWorkers
+	if w <= 1 || e.forked || n < e.opts.ParallelThreshold || n < 2 {
+		return 1
+	}
+	if w > n {
+		w = n
+	}
+	return w
+}
+
+// Clone returns an Env with the options, key order, context and limits of e, and none of its work nor error.
+func (e *Env) Clone() *Env {
+	return &Env{
+		opts:     e.opts,
+		tags:     e.tags,
+		keyOrder: e.keyOrder,
+		ctx:      e.ctx,
+		limits:   e.limits,
+	}
+}
+
+// fork returns an Env to evaluate a part of a parallel evaluation with in another goroutine,
+// sharing e's budgets and ancestors as they are, and aborting once ctx is done.
+func (e *Env) fork(ctx context.Context) *Env {
+	child := &Env{
+		opts:     e.opts,
+		tags:     e.tags,
+		keyOrder: e.keyOrder,
+		ctx:      ctx,
+		limits:   e.limits,
+		visited:  e.visited,
+		results:  e.results,
+		ancestor: make(map[cycleKey]struct{}, len(e.ancestor)),
+		forked:   true,
+	}
+	for k := range e.ancestor {
+		child.ancestor[k] = struct{}{}
+	}
+	return child
+}
+
+// join adds the work of the Envs forked from e to e, and aborts e with the first error in
+// the order of children, ignoring the cancellation of ctx the children were forked with.
+func (e *Env) join(ctx context.Context, children []*Env) error {
+	visited, results := e.visited, e.results
+	var err error
+	for _, child := range children {
+		e.visited += child.visited - visited
+		e.results += child.results - results
+		if err == nil && child.err != nil && (child.err != ctx.Err() || e.ctx != nil && e.ctx.Err() != nil) {
+			err = child.err
+		}
+	}
+	if err != nil {
+		return e.abort(err)
+	}
+	if e.limits.MaxVisited > 0 && e.visited > e.limits.MaxVisited {
+		return e.abort(&LimitError{Kind: LimitVisited, Limit: e.limits.MaxVisited})
+	}
+	if e.limits.MaxResults > 0 && e.results > e.limits.MaxResults {
+		return e.abort(&LimitError{Kind: LimitResults, Limit: e.limits.MaxResults})
+	}
+	return nil
+}
+
+func (e *Env) checkDepth(depth int) error {
+	if e.limits.MaxDepth > 0 && depth > e.limits.MaxDepth {
+		return e.abort(&LimitError{Kind: LimitDepth, Limit: e.limits.MaxDepth})
+	}
+	return nil
+}
+
+// enter marks a pointer, map or slice as being descended into and reports false if it already is,
+// i.e. the value is reachable from itself. A marked value must be released by leave once the descent ends.
+func (e *Env) enter(value reflect.Value) (cycleKey, bool) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Map:
+		if value.IsNil() {
+			return cycleKey{}, true
+		}
+	case reflect.Slice:
+		if value.Len() == 0 {
+			return cycleKey{}, true
+		}
+	default:
+		return cycleKey{}, true
+	}
+	key := cycleKey{ptr: value.Pointer(), typ: value.Type()}
+	if value.Kind() == reflect.Slice {
+		key.len = value.Len()
+	}
+	return key, e.enterKey(key)
+}
+
+func (e *Env) enterKey(key cycleKey) bool {
+	if _, ok := e.ancestor[key]; ok {
+		return false
+	}
+	if e.ancestor == nil {
+		e.ancestor = make(map[cycleKey]struct{})
+	}
+	e.ancestor[key] = struct{}{}
+	return true
+}
+
+func (e *Env) leave(key cycleKey) {
+	if key.ptr != 0 {
+		delete(e.ancestor, key)
+	}
+}
+
+func (e *Env) rangeMap(value reflect.Value, fn func(key, elem reflect.Value) bool) {
+	switch e.opts.MapOrder {
+	case MapOrderRandom:
+		iter := value.MapRange()
+		for iter.Next() {
+			if !fn(iter.Key(), iter.Value()) {
+				return
+			}
+		}
+		return
+	case MapOrderDocument:
+		if e.rangeDocumentMap(value, fn) {
+			return
+		}
+	}
+	// The elements are taken from MapRange, as MapIndex can not find the keys not equal to themselves, like NaN.
+	keys := make([]reflect.Value, 0, value.Len())
+	elems := make([]reflect.Value, 0, value.Len())
+	names := make([]string, 0, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		keys = append(keys, iter.Key())
+		elems = append(elems, iter.Value())
+		names = append(names, mapKeyString(iter.Key()))
+	}
+	sort.Sort(&keySorter{keys: keys, elems: elems, names: names})
+	for i, k := range keys {
+		if !fn(k, elems[i]) {
+			return
+		}
+	}
+}
+
+func (e *Env) rangeDocumentMap(value reflect.Value, fn func(key, elem reflect.Value) bool) bool {
+	keys, ok := e.keyOrder[value.Pointer()]
+	if !ok || len(keys) != value.Len() || value.Type().Key().Kind() != reflect.String {
+		return false
+	}
+	keyType := value.Type().Key()
+	for _, k := range keys {
+		key := reflect.ValueOf(k).Convert(keyType)
+		elem := value.MapIndex(key)
+		if !elem.IsValid() {
+			continue
+		}
+		if !fn(key, elem) {
+			break
+		}
+	}
+	return true
+}
+
+func (e *Env) rangeObject(m map[string]interface{}, fn func(key string, elem interface{}) bool) {
+	switch e.opts.MapOrder {
+	case MapOrderRandom:
+		for k, v := range m {
+			if !fn(k, v) {
+				return
+			}
+		}
+		return
+	case MapOrderDocument:
+		if keys, ok := e.keyOrder[reflect.ValueOf(m).Pointer()]; ok && len(keys) == len(m) {
+			for _, k := range keys {
+				v, ok := m[k]
+				if !ok {
+					continue
+				}
+				if !fn(k, v) {
+					return
+				}
+			}
+			return
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !fn(k, m[k]) {
+			return
+		}
+	}
+}
+
+func mapKeyString(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if tm, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if key.Kind() == reflect.Ptr && key.IsNil() {
+			return ""
+		}
+		b, err := tm.MarshalText()
+		if err == nil {
+			return string(b)
+		}
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10)
+	default:
+		return fmt.Sprint(key.Interface())
+	}
+}
+
+type keySorter struct {
+	keys  []reflect.Value
+	elems []reflect.Value
+	names []string
+}
+
+func (s *keySorter) Len() int {
+	return len(s.keys)
+}
+
+func (s *keySorter) Less(i, j int) bool {
+	return s.names[i] < s.names[j]
+}
+
+func (s *keySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.elems[i], s.elems[j] = s.elems[j], s.elems[i]
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+}
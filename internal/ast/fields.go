@@ -0,0 +1,357 @@
+package ast
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unsafe"
+)
+
+type field struct {
+	name       string
+	tag        bool
+	index      []int
+	typ        reflect.Type
+	omitEmpty  bool
+	omitZero   bool
+	quoted     bool
+	unexported bool
+	sf         reflect.StructField
+}
+
+type structFields struct {
+	list   []field
+	byName map[string]int
+}
+
+var typeOfInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+
+var fieldCache sync.Map // map[fieldCacheKey]*structFields
+
+type fieldCacheKey struct {
+	t          reflect.Type
+	tags       string
+	unexported bool
+}
+
+// cachedTypeFields is like typeFields but uses a cache to avoid repeated work.
+// tags is the comma separated list of tag keys to read names and options from, by priority.
+func cachedTypeFields(t reflect.Type, tags string, unexported bool) *structFields {
+	key := fieldCacheKey{t: t, tags: tags, unexported: unexported}
+	if f, ok := fieldCache.Load(key); ok {
+		return f.(*structFields)
+	}
+	f, _ := fieldCache.LoadOrStore(key, typeFields(t, strings.Split(tags, ","), unexported))
+	return f.(*structFields)
+}
+
+// typeFields returns the fields of struct type t that encoding/json would marshal,
+// following its rules for embedded structs and name conflicts. Fields tagged `jsonpath:"-"`
+// are never returned, unexported fields are returned if unexported is set.
+func typeFields(t reflect.Type, tags []string, unexported bool) *structFields {
+	current := []field{}
+	next := []field{{typ: t}}
+
+	var count, nextCount map[reflect.Type]int
+	visited := map[reflect.Type]bool{}
+
+	var fields []field
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, f := range current {
+			if visited[f.typ] {
+				continue
+			}
+			visited[f.typ] = true
+
+			for i := 0; i < f.typ.NumField(); i++ {
+				sf := f.typ.Field(i)
+				if sf.Tag.Get("jsonpath") == "-" {
+					continue
+				}
+				if sf.Anonymous {
+					t := sf.Type
+					if t.Kind() == reflect.Ptr {
+						t = t.Elem()
+					}
+					if !sf.IsExported() && t.Kind() != reflect.Struct && !unexported {
+						continue
+					}
+				} else if !sf.IsExported() && !unexported {
+					continue
+				}
+				tag := lookupTag(sf.Tag, tags)
+				if tag == "-" {
+					continue
+				}
+				name, opts := parseTag(tag)
+				if !isValidTag(name) {
+					name = ""
+				}
+				index := make([]int, len(f.index)+1)
+				copy(index, f.index)
+				index[len(f.index)] = i
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				quoted := false
+				if opts.contains("string") {
+					switch ft.Kind() {
+					case reflect.Bool,
+						reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+						reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+						reflect.Float32, reflect.Float64,
+						reflect.String:
+						quoted = true
+					}
+				}
+
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					tagged := name != ""
+					if name == "" {
+						name = sf.Name
+					}
+					fields = append(fields, field{
+						name:       name,
+						tag:        tagged,
+						index:      index,
+						typ:        ft,
+						omitEmpty:  opts.contains("omitempty"),
+						omitZero:   opts.contains("omitzero"),
+						quoted:     quoted,
+						unexported: f.unexported || !sf.IsExported(),
+						sf:         sf,
+					})
+					if count[f.typ] > 1 {
+						// If there were multiple instances, add a second,
+						// so that the annihilation code will see a duplicate.
+						fields = append(fields, fields[len(fields)-1])
+					}
+					continue
+				}
+
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, field{name: ft.Name(), index: index, typ: ft, unexported: f.unexported || !sf.IsExported()})
+				}
+			}
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		x := fields
+		if x[i].name != x[j].name {
+			return x[i].name < x[j].name
+		}
+		if len(x[i].index) != len(x[j].index) {
+			return len(x[i].index) < len(x[j].index)
+		}
+		if x[i].tag != x[j].tag {
+			return x[i].tag
+		}
+		return byIndex(x).Less(i, j)
+	})
+
+	// Delete all fields that are hidden by the Go rules for embedded fields,
+	// except that fields with JSON tags are promoted.
+	out := fields[:0]
+	for advance, i := 0, 0; i < len(fields); i += advance {
+		fi := fields[i]
+		name := fi.name
+		for advance = 1; i+advance < len(fields); advance++ {
+			fj := fields[i+advance]
+			if fj.name != name {
+				break
+			}
+		}
+		if advance == 1 {
+			out = append(out, fi)
+			continue
+		}
+		dominant, ok := dominantField(fields[i : i+advance])
+		if ok {
+			out = append(out, dominant)
+		}
+	}
+
+	fields = out
+	sort.Sort(byIndex(fields))
+
+	byName := make(map[string]int, len(fields))
+	for i, f := range fields {
+		byName[f.name] = i
+	}
+	return &structFields{
+		list:   fields,
+		byName: byName,
+	}
+}
+
+func lookupTag(tag reflect.StructTag, keys []string) string {
+	for _, k := range keys {
+		if v, ok := tag.Lookup(k); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// lookup returns the field with the given name, falling back to a case-insensitive
+// match like encoding/json does when unmarshaling if caseInsensitive is set.
+func (s *structFields) lookup(name string, caseInsensitive bool) (*field, bool) {
+	if i, ok := s.byName[name]; ok {
+		return &s.list[i], true
+	}
+	if caseInsensitive {
+		for i := range s.list {
+			if strings.EqualFold(s.list[i].name, name) {
+				return &s.list[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// dominantField looks through the fields, all of which are known to have the same name,
+// to find the single field that dominates the others using Go's embedding rules,
+// modified by the presence of JSON tags.
+func dominantField(fields []field) (field, bool) {
+	if len(fields) > 1 && len(fields[0].index) == len(fields[1].index) && fields[0].tag == fields[1].tag {
+		return field{}, false
+	}
+	return fields[0], true
+}
+
+type byIndex []field
+
+func (x byIndex) Len() int { return len(x) }
+
+func (x byIndex) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+
+func (x byIndex) Less(i, j int) bool {
+	for k, xik := range x[i].index {
+		if k >= len(x[j].index) {
+			return false
+		}
+		if xik != x[j].index[k] {
+			return xik < x[j].index[k]
+		}
+	}
+	return len(x[i].index) < len(x[j].index)
+}
+
+// value returns the value of the field in struct v the way encoding/json would marshal it.
+// It reports false if the field is omitted, either by its options or by a nil embedded pointer.
+func (f *field) value(v reflect.Value) (reflect.Value, bool) {
+	if f.unexported && !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	for i, x := range f.index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+		if f.unexported && !v.CanInterface() && v.CanAddr() {
+			v = reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+		}
+	}
+	if f.omitEmpty && isEmptyValue(v) || f.omitZero && isZeroValue(v) {
+		return reflect.Value{}, false
+	}
+	if f.quoted {
+		return quote(v), true
+	}
+	return v, true
+}
+
+func quote(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Zero(typeOfInterface)
+		}
+		v = v.Elem()
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return v
+	}
+	return reflect.ValueOf(string(b))
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Ptr:
+		return v.IsZero()
+	}
+	return false
+}
+
+func isZeroValue(v reflect.Value) bool {
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return true
+		}
+		return z.IsZero()
+	}
+	return v.IsZero()
+}
+
+type tagOptions string
+
+func parseTag(tag string) (string, tagOptions) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, tagOptions(opts)
+}
+
+func (o tagOptions) contains(optionName string) bool {
+	if len(o) == 0 {
+		return false
+	}
+	s := string(o)
+	for s != "" {
+		var name string
+		name, s, _ = strings.Cut(s, ",")
+		if name == optionName {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but
+			// otherwise any punctuation chars are allowed
+			// in a tag name.
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
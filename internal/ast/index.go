@@ -21,20 +21,51 @@ func (i *Index) String() string {
 	return fmt.Sprintf("[%d]%s", i.index, i.next.String())
 }
 
-func (i *Index) Get(data interface{}) (*Result, error) {
+func (i *Index) Get(env *Env, data interface{}) (*Result, error) {
+	if err := env.visit(); err != nil {
+		return nil, err
+	}
+	data, err := env.resolve(data)
+	if err != nil {
+		return nil, err
+	}
+	if a, ok := data.([]interface{}); ok {
+		idx := normalizeIndex(i.index, len(a))
+		if idx < 0 || idx >= len(a) {
+			return nil, notFound("index %d not found", i.index)
+		}
+		return i.next.Get(env, a[idx])
+	}
+	if a, ok := lookupAdapter(data); ok {
+		if a.Kind(data) != KindArray {
+			return nil, notFound("could not get index %d of type %T", i.index, data)
+		}
+		idx := i.index
+		if idx < 0 {
+			idx = normalizeIndex(idx, a.Len(data))
+		}
+		if idx < 0 {
+			return nil, notFound("index %d not found", i.index)
+		}
+		v, ok := a.Index(data, idx)
+		if !ok {
+			return nil, notFound("index %d not found", i.index)
+		}
+		return i.next.Get(env, v)
+	}
 	value := reflect.ValueOf(data)
 	for value.Kind() == reflect.Ptr {
 		if value.IsNil() {
-			return nil, fmt.Errorf("index %d not found", i.index)
+			return nil, notFound("index %d not found", i.index)
 		}
 		value = value.Elem()
 	}
 	if value.Kind() != reflect.Array && value.Kind() != reflect.Slice {
-		return nil, fmt.Errorf("could not get index %d of type %s", i.index, value.Kind())
+		return nil, notFound("could not get index %d of type %s", i.index, value.Kind())
 	}
-	idx := (i.index + value.Len()) % value.Len()
+	idx := normalizeIndex(i.index, value.Len())
 	if idx < 0 || idx >= value.Len() {
-		return nil, fmt.Errorf("index %d not found", i.index)
+		return nil, notFound("index %d not found", i.index)
 	}
-	return i.next.Get(value.Index(idx).Interface())
+	return i.next.Get(env, value.Index(idx).Interface())
 }
@@ -0,0 +1,50 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type Limits struct {
+	MaxResults int
+	MaxDepth   int
+	MaxVisited int
+}
+
+type LimitKind int
+
+const (
+	LimitResults LimitKind = iota + 1
+	LimitDepth
+	LimitVisited
+)
+
+func (k LimitKind) String() string {
+	switch k {
+	case LimitResults:
+		return "results"
+	case LimitDepth:
+		return "depth"
+	case LimitVisited:
+		return "visited nodes"
+	default:
+		return fmt.Sprintf("LimitKind(%d)", int(k))
+	}
+}
+
+type LimitError struct {
+	Kind  LimitKind
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("jsonpath: max %s %d exceeded", e.Kind, e.Limit)
+}
+
+type CycleError struct {
+	Type reflect.Type
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("jsonpath: cycle detected at %s", e.Type)
+}
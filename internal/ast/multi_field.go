@@ -7,14 +7,20 @@ import (
 )
 
 type MultiFields struct {
-	fields []string
-	next   Node
+	fields  []string
+	singles []*SingleField
+	next    Node
 }
 
 func NewMultiFields(fields []string, next Node) *MultiFields {
+	singles := make([]*SingleField, 0, len(fields))
+	for _, f := range fields {
+		singles = append(singles, NewSingleField(f, next))
+	}
 	return &MultiFields{
-		fields: fields,
-		next:   next,
+		fields:  fields,
+		singles: singles,
+		next:    next,
 	}
 }
 
@@ -32,8 +38,8 @@ func (m *MultiFields) String() string {
 	return builder.String()
 }
 
-func (m *MultiFields) Get(data interface{}) (*Result, error) {
-	r, err := m.get(data)
+func (m *MultiFields) Get(env *Env, data interface{}) (*Result, error) {
+	r, err := m.get(env, data)
 	if err != nil {
 		return nil, err
 	}
@@ -43,28 +49,43 @@ func (m *MultiFields) Get(data interface{}) (*Result, error) {
 	}, nil
 }
 
-func (m *MultiFields) get(data interface{}) ([]interface{}, error) {
+func (m *MultiFields) get(env *Env, data interface{}) ([]interface{}, error) {
+	data, err := env.resolve(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := data.(map[string]interface{}); ok {
+		return m.getObject(env, data)
+	}
+	if a, ok := lookupAdapter(data); ok {
+		if a.Kind(data) != KindObject {
+			return nil, notFound("unsupported get field %s from %T", m, data)
+		}
+		return m.getObject(env, data)
+	}
 	value := reflect.ValueOf(data)
 	for value.Kind() == reflect.Ptr {
 		if value.IsNil() {
-			return nil, fmt.Errorf("can not find %s from nil", m)
+			return nil, notFound("can not find %s from nil", m)
 		}
 		value = value.Elem()
 	}
 	switch value.Kind() {
 	case reflect.Map, reflect.Struct:
-		return m.getObject(value)
+		return m.getObject(env, data)
 	default:
-		return nil, fmt.Errorf("unsupported get field %s from %s", m, value.Kind())
+		return nil, notFound("unsupported get field %s from %s", m, value.Kind())
 	}
 }
 
-func (m *MultiFields) getObject(value reflect.Value) ([]interface{}, error) {
-	data := value.Interface()
+func (m *MultiFields) getObject(env *Env, data interface{}) ([]interface{}, error) {
 	result := make([]interface{}, 0, len(m.fields))
-	for _, field := range m.fields {
-		r, err := NewSingleField(field, m.next).Get(data)
+	for _, field := range m.singles {
+		r, err := field.Get(env, data)
 		if err != nil {
+			if env.Err() != nil {
+				return nil, err
+			}
 			continue
 		}
 		if r.multi {
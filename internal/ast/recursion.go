@@ -1,8 +1,17 @@
 package ast
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"sync"
+	"unsafe"
+)
+
+var (
+	typeOfObject = reflect.TypeOf(map[string]interface{}(nil))
+	typeOfArray  = reflect.TypeOf([]interface{}(nil))
 )
 
 type Recursion struct {
@@ -19,9 +28,9 @@ func (r *Recursion) String() string {
 	return fmt.Sprintf("..%s", r.next.String())
 }
 
-func (r *Recursion) Get(data interface{}) (*Result, error) {
+func (r *Recursion) Get(env *Env, data interface{}) (*Result, error) {
 	result := make([]interface{}, 0)
-	result, err := r.get(reflect.ValueOf(data), result)
+	result, err := r.getAny(env, data, 0, result)
 	if err != nil {
 		return nil, err
 	}
@@ -31,16 +40,98 @@ func (r *Recursion) Get(data interface{}) (*Result, error) {
 	}, nil
 }
 
-func (r *Recursion) get(value reflect.Value, result []interface{}) ([]interface{}, error) {
+func (r *Recursion) getAny(env *Env, data interface{}, depth int, result []interface{}) ([]interface{}, error) {
+	data, err := env.resolve(data)
+	if err != nil {
+		return nil, err
+	}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if err := env.visit(); err != nil {
+			return nil, err
+		}
+		if err := env.checkDepth(depth); err != nil {
+			return nil, err
+		}
+		key := cycleKey{ptr: reflect.ValueOf(v).Pointer(), typ: typeOfObject}
+		if v != nil && !env.enterKey(key) {
+			return r.onCycle(env, typeOfObject, result)
+		}
+		defer env.leave(key)
+		return r.getObject(env, v, depth, result)
+	case []interface{}:
+		if err := env.visit(); err != nil {
+			return nil, err
+		}
+		if err := env.checkDepth(depth); err != nil {
+			return nil, err
+		}
+		var key cycleKey
+		if len(v) > 0 {
+			key = cycleKey{ptr: uintptr(unsafe.Pointer(&v[0])), len: len(v), typ: typeOfArray}
+			if !env.enterKey(key) {
+				return r.onCycle(env, typeOfArray, result)
+			}
+		}
+		defer env.leave(key)
+		return r.getSlice(env, data, v, depth, result)
+	case string, json.Number, float64, bool, nil:
+		return result, env.visit()
+	}
+	if a, ok := lookupAdapter(data); ok {
+		if err := env.visit(); err != nil {
+			return nil, err
+		}
+		if a.Kind(data) == KindScalar {
+			return result, nil
+		}
+		if err := env.checkDepth(depth); err != nil {
+			return nil, err
+		}
+		key, ok := env.enter(reflect.ValueOf(data))
+		if !ok {
+			return r.onCycle(env, reflect.TypeOf(data), result)
+		}
+		defer env.leave(key)
+		return r.getAdapter(env, a, data, depth, result)
+	}
+	return r.get(env, reflect.ValueOf(data), depth, result)
+}
+
+func (r *Recursion) onCycle(env *Env, t reflect.Type, result []interface{}) ([]interface{}, error) {
+	if env.opts.CyclePolicy == CycleSkip {
+		return result, nil
+	}
+	return nil, env.abort(&CycleError{Type: t})
+}
+
+func (r *Recursion) get(env *Env, value reflect.Value, depth int, result []interface{}) ([]interface{}, error) {
+	value, err := env.resolveValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if value.IsValid() && value.CanInterface() && value.Kind() != reflect.Interface {
+		if _, ok := lookupAdapterType(value.Type()); ok {
+			return r.getAny(env, value.Interface(), depth, result)
+		}
+	}
+	if err := env.visit(); err != nil {
+		return nil, err
+	}
+	key, ok := env.enter(value)
+	if !ok {
+		return r.onCycle(env, value.Type(), result)
+	}
+	defer env.leave(key)
 	for value.Kind() == reflect.Ptr {
 		if value.IsNil() {
-			return nil, nil
+			return result, nil
 		}
 		value = value.Elem()
 	}
 
 	switch value.Kind() {
-	case reflect.Bool,
+	case reflect.Invalid, reflect.Bool,
 		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
 		reflect.Uintptr,
@@ -48,77 +139,197 @@ func (r *Recursion) get(value reflect.Value, result []interface{}) ([]interface{
 		reflect.Complex64, reflect.Complex128,
 		reflect.String:
 		return result, nil
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.Struct:
+		if err := env.checkDepth(depth); err != nil {
+			return nil, err
+		}
+	}
+
+	switch value.Kind() {
 	case reflect.Array, reflect.Slice:
-		return r.getArray(value, result), nil
+		return r.getArray(env, value, depth, result)
 	case reflect.Map:
-		return r.getMap(value, result), nil
+		return r.getMap(env, value, depth, result)
 	case reflect.Struct:
-		return r.getStruct(value, result), nil
+		return r.getStruct(env, value, depth, result)
 	case reflect.Interface:
-		return r.get(reflect.ValueOf(value.Interface()), result)
+		return r.getAny(env, value.Interface(), depth, result)
 	default:
-		return nil, fmt.Errorf("unsupported get field %s from %s", r, value.Kind().String())
+		return nil, notFound("unsupported get field %s from %s", r, value.Kind().String())
 	}
 }
 
-func (r *Recursion) getMap(value reflect.Value, result []interface{}) []interface{} {
-	t, err := r.next.Get(value.Interface())
-	if err == nil {
-		if t.multi {
-			result = append(result, t.data.([]interface{})...)
-		} else {
-			result = append(result, t.data)
-		}
+func (r *Recursion) getNext(env *Env, data interface{}, result []interface{}) ([]interface{}, error) {
+	t, err := r.next.Get(env, data)
+	if err != nil {
+		return result, env.Err()
 	}
-	iter := value.MapRange()
-	for iter.Next() {
-		r, err := r.get(iter.Value(), result)
-		if err != nil {
-			continue
-		}
-		result = r
+	if t.multi {
+		result = append(result, t.data.([]interface{})...)
+	} else {
+		result = append(result, t.data)
 	}
-	return result
+	return result, nil
 }
 
-func (r *Recursion) getStruct(value reflect.Value, result []interface{}) []interface{} {
-	t, err := r.next.Get(value.Interface())
-	if err == nil {
-		if t.multi {
-			result = append(result, t.data.([]interface{})...)
-		} else {
-			result = append(result, t.data)
+func (r *Recursion) getObject(env *Env, m map[string]interface{}, depth int, result []interface{}) ([]interface{}, error) {
+	result, err := r.getNext(env, m, result)
+	if err != nil {
+		return nil, err
+	}
+	env.rangeObject(m, func(_ string, elem interface{}) bool {
+		t, e := r.getAny(env, elem, depth+1, result)
+		if e != nil {
+			err = env.Err()
+			return err == nil
 		}
+		result = t
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
-	for i := 0; i < value.NumField(); i++ {
-		_, omitempty := getFieldKey(value.Type().Field(i))
-		if omitempty && value.Field(i).IsZero() {
-			continue
+	return result, nil
+}
+
+func (r *Recursion) getSlice(env *Env, data interface{}, s []interface{}, depth int, result []interface{}) ([]interface{}, error) {
+	result, err := r.getNext(env, data, result)
+	if err != nil {
+		return nil, err
+	}
+	return r.getElems(env, len(s), func(env *Env, i int, result []interface{}) ([]interface{}, error) {
+		return r.getAny(env, s[i], depth+1, result)
+	}, result)
+}
+
+// getElems calls get for the n elements of an array in order, with goroutines if env allows it.
+func (r *Recursion) getElems(env *Env, n int, get func(env *Env, i int, result []interface{}) ([]interface{}, error), result []interface{}) ([]interface{}, error) {
+	workers := env.workers(n)
+	if workers == 1 {
+		for i := 0; i < n; i++ {
+			t, err := get(env, i, result)
+			if err != nil {
+				if env.Err() != nil {
+					return nil, err
+				}
+				continue
+			}
+			result = t
 		}
-		r, err := r.get(value.Field(i), result)
-		if err != nil {
-			continue
+		return result, nil
+	}
+
+	parent := env.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	children := make([]*Env, workers)
+	results := make([][]interface{}, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		children[w] = env.fork(ctx)
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			child := children[w]
+			for i := w * n / workers; i < (w+1)*n/workers; i++ {
+				t, err := get(child, i, results[w])
+				if err != nil {
+					if child.Err() != nil {
+						cancel()
+						return
+					}
+					continue
+				}
+				results[w] = t
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := env.join(ctx, children); err != nil {
+		return nil, err
+	}
+	for _, t := range results {
+		result = append(result, t...)
+	}
+	return result, nil
+}
+
+func (r *Recursion) getAdapter(env *Env, a Adapter, data interface{}, depth int, result []interface{}) ([]interface{}, error) {
+	result, err := r.getNext(env, data, result)
+	if err != nil {
+		return nil, err
+	}
+	a.Range(data, func(_ interface{}, elem interface{}) bool {
+		t, e := r.getAny(env, elem, depth+1, result)
+		if e != nil {
+			err = env.Err()
+			return err == nil
 		}
-		result = r
+		result = t
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
-	return result
+	return result, nil
 }
 
-func (r *Recursion) getArray(value reflect.Value, result []interface{}) []interface{} {
-	t, err := r.next.Get(value.Interface())
-	if err == nil {
-		if t.multi {
-			result = append(result, t.data.([]interface{})...)
-		} else {
-			result = append(result, t.data)
+func (r *Recursion) getMap(env *Env, value reflect.Value, depth int, result []interface{}) ([]interface{}, error) {
+	result, err := r.getNext(env, value.Interface(), result)
+	if err != nil {
+		return nil, err
+	}
+	env.rangeMap(value, func(_, elem reflect.Value) bool {
+		t, e := r.get(env, elem, depth+1, result)
+		if e != nil {
+			err = env.Err()
+			return err == nil
 		}
+		result = t
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *Recursion) getStruct(env *Env, value reflect.Value, depth int, result []interface{}) ([]interface{}, error) {
+	result, err := r.getNext(env, value.Interface(), result)
+	if err != nil {
+		return nil, err
 	}
-	for i := 0; i < value.Len(); i++ {
-		r, err := r.get(value.Index(i), result)
+	fields := env.typeFields(value.Type())
+	for i := range fields.list {
+		f := &fields.list[i]
+		if !env.fieldVisible(f) {
+			continue
+		}
+		fv, ok := f.value(value)
+		if !ok {
+			continue
+		}
+		t, err := r.get(env, fv, depth+1, result)
 		if err != nil {
+			if env.Err() != nil {
+				return nil, err
+			}
 			continue
 		}
-		result = r
+		result = t
+	}
+	return result, nil
+}
+
+func (r *Recursion) getArray(env *Env, value reflect.Value, depth int, result []interface{}) ([]interface{}, error) {
+	result, err := r.getNext(env, value.Interface(), result)
+	if err != nil {
+		return nil, err
 	}
-	return result
+	return r.getElems(env, value.Len(), func(env *Env, i int, result []interface{}) ([]interface{}, error) {
+		return r.get(env, value.Index(i), depth+1, result)
+	}, result)
 }
@@ -0,0 +1,111 @@
+package ast
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"reflect"
+)
+
+// Unwrapper is implemented by wrapper types, such as optional values, that stand for the value
+// they hold. ok is false if the wrapper holds no valid value, which is then treated as null.
+type Unwrapper interface {
+	Unwrap() (v interface{}, ok bool)
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	unwrapperType     = reflect.TypeOf((*Unwrapper)(nil)).Elem()
+	valuerType        = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+const maxUnwrap = 8
+
+// resolve replaces a wrapper by the value it holds when Options.Unwrap is set, and a json.Marshaler
+// or encoding.TextMarshaler by its decoded JSON form when Options.UseMarshalers is set.
+func (e *Env) resolve(data interface{}) (interface{}, error) {
+	if !e.opts.UseMarshalers && !e.opts.Unwrap {
+		return data, nil
+	}
+	for i := 0; ; i++ {
+		switch data.(type) {
+		case map[string]interface{}, []interface{}, string, json.Number, float64, bool, nil:
+			return data, nil
+		}
+		t := reflect.TypeOf(data)
+		if _, ok := lookupAdapterType(t); ok {
+			return data, nil
+		}
+		if e.opts.Unwrap && i < maxUnwrap && (t.Implements(unwrapperType) || t.Implements(valuerType)) {
+			v, err := unwrap(data)
+			if err != nil {
+				return nil, err
+			}
+			data = v
+			continue
+		}
+		if e.opts.UseMarshalers && (t.Implements(marshalerType) || t.Implements(textMarshalerType)) {
+			return marshaled(data)
+		}
+		return data, nil
+	}
+}
+
+func (e *Env) resolveValue(value reflect.Value) (reflect.Value, error) {
+	if !e.opts.UseMarshalers && !e.opts.Unwrap || !value.IsValid() || !value.CanInterface() {
+		return value, nil
+	}
+	t := value.Type()
+	if t.Kind() == reflect.Interface {
+		return value, nil
+	}
+	if !(e.opts.Unwrap && (t.Implements(unwrapperType) || t.Implements(valuerType))) &&
+		!(e.opts.UseMarshalers && (t.Implements(marshalerType) || t.Implements(textMarshalerType))) {
+		return value, nil
+	}
+	data, err := e.resolve(value.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.ValueOf(data), nil
+}
+
+func unwrap(data interface{}) (interface{}, error) {
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	if u, ok := data.(Unwrapper); ok {
+		v, ok := u.Unwrap()
+		if !ok {
+			return nil, nil
+		}
+		return v, nil
+	}
+	return data.(driver.Valuer).Value()
+}
+
+func marshaled(data interface{}) (interface{}, error) {
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	if m, ok := data.(json.Marshaler); ok {
+		b, err := m.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	b, err := data.(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
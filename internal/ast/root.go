@@ -16,6 +16,6 @@ func (r *Root) String() string {
 	return fmt.Sprintf("$%s", r.next.String())
 }
 
-func (r *Root) Get(data interface{}) (*Result, error) {
-	return r.next.Get(data)
+func (r *Root) Get(env *Env, data interface{}) (*Result, error) {
+	return r.next.Get(env, data)
 }
@@ -0,0 +1,124 @@
+package ast
+
+import "math"
+
+// Selector is a segment of a path, used to evaluate it one member or element at a time.
+// Descendant reports whether the segment applies to all the descendants of a value, like `..`.
+// Containers reports whether the segment is a `..` ending the path, which selects the value and
+// its descendants that are objects or arrays, and is also Descendant.
+type Selector struct {
+	Descendant bool
+	Containers bool
+	wildcard   bool
+	names      []string
+	indexes    []int
+	slices     []*Slice
+}
+
+// Selectors returns the segments of the path in order.
+func (a *AST) Selectors() []Selector {
+	selectors := make([]Selector, 0)
+	descendant := false
+	for n := a.node; n != nil; {
+		s := Selector{Descendant: descendant}
+		descendant = false
+		switch t := n.(type) {
+		case *Root:
+			n = t.next
+			continue
+		case *Recursion:
+			descendant = true
+			n = t.next
+			continue
+		case *SingleField:
+			s.names = []string{t.field}
+			n = t.next
+		case *MultiFields:
+			s.names = t.fields
+			n = t.next
+		case *All:
+			s.wildcard = true
+			n = t.next
+		case *Index:
+			s.indexes = []int{t.index}
+			n = t.next
+		case *Slice:
+			s.slices = []*Slice{t}
+			n = t.next
+		case *Indexes:
+			for _, item := range t.nodes {
+				switch t := item.(type) {
+				case *Index:
+					s.indexes = append(s.indexes, t.index)
+					n = t.next
+				case *Slice:
+					s.slices = append(s.slices, t)
+					n = t.next
+				}
+			}
+		default:
+			if s.Descendant {
+				s.Containers = true
+				selectors = append(selectors, s)
+			}
+			n = nil
+			continue
+		}
+		selectors = append(selectors, s)
+	}
+	return selectors
+}
+
+// MatchName reports whether the segment selects the member name of an object.
+func (s *Selector) MatchName(name string) bool {
+	if s.wildcard {
+		return true
+	}
+	for _, n := range s.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsLen reports whether MatchIndex needs the length of the array, for negative indexes and bounds.
+func (s *Selector) NeedsLen() bool {
+	for _, i := range s.indexes {
+		if i < 0 {
+			return true
+		}
+	}
+	for _, sl := range s.slices {
+		if sl.start != nil && *sl.start < 0 || sl.end != nil && *sl.end < 0 || sl.step != nil && *sl.step < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchIndex reports whether the segment selects the element i of an array of length n.
+// n may be negative if it is not known and NeedsLen is false.
+func (s *Selector) MatchIndex(i, n int) bool {
+	if s.wildcard {
+		return true
+	}
+	if n < 0 {
+		n = math.MaxInt
+	}
+	for _, idx := range s.indexes {
+		if normalizeIndex(idx, n) == i {
+			return true
+		}
+	}
+	for _, sl := range s.slices {
+		lower, upper, step := sl.bounds(n)
+		switch {
+		case step > 0 && i >= lower && i < upper && (i-lower)%step == 0:
+			return true
+		case step < 0 && i <= lower && i > upper && (lower-i)%(-step) == 0:
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 type SingleField struct {
@@ -22,7 +23,27 @@ func (s *SingleField) String() string {
 	return fmt.Sprintf("[%q]%s", s.field, s.next.String())
 }
 
-func (s *SingleField) Get(data interface{}) (*Result, error) {
+func (s *SingleField) Get(env *Env, data interface{}) (*Result, error) {
+	if err := env.visit(); err != nil {
+		return nil, err
+	}
+	data, err := env.resolve(data)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := data.(map[string]interface{}); ok {
+		v, ok := m[s.field]
+		if !ok && env.opts.CaseInsensitive {
+			v, ok = s.foldObject(env, m)
+		}
+		if !ok {
+			return nil, s.errNotFound()
+		}
+		return s.next.Get(env, v)
+	}
+	if a, ok := lookupAdapter(data); ok {
+		return s.getAdapter(env, a, data)
+	}
 	value := reflect.ValueOf(data)
 	for value.Kind() == reflect.Ptr {
 		if value.IsNil() {
@@ -32,93 +53,171 @@ func (s *SingleField) Get(data interface{}) (*Result, error) {
 	}
 	switch value.Kind() {
 	case reflect.Map:
-		return s.getMap(value)
+		return s.getMap(env, value)
 	case reflect.Struct:
-		return s.getStruct(value)
+		return s.getStruct(env, value)
 	default:
-		return nil, fmt.Errorf("unsupported get field %s from %s", s.field, value.Kind())
+		return nil, notFound("unsupported get field %s from %s", s.field, value.Kind())
 	}
 }
 
 func (s *SingleField) errNotFound() error {
-	return fmt.Errorf("%s not found", s.field)
+	return notFound("%s not found", s.field)
 }
 
-func (s *SingleField) getMap(value reflect.Value) (*Result, error) {
-	key := reflect.ValueOf(s.field)
-	switch t := value.Type().Key().Kind(); t {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		i, err := strconv.ParseInt(s.field, 10, 64)
-		if err != nil {
-			return nil, s.errNotFound()
+func (s *SingleField) getAdapter(env *Env, a Adapter, data interface{}) (*Result, error) {
+	if k := a.Kind(data); k != KindObject {
+		return nil, notFound("unsupported get field %s from %T", s.field, data)
+	}
+	v, ok := a.Field(data, s.field)
+	if !ok && env.opts.CaseInsensitive {
+		for _, k := range a.Keys(data) {
+			if strings.EqualFold(k, s.field) {
+				v, ok = a.Field(data, k)
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, s.errNotFound()
+	}
+	return s.next.Get(env, v)
+}
+
+// foldObject returns the first member, in map order, whose key matches the field case-insensitively.
+func (s *SingleField) foldObject(env *Env, m map[string]interface{}) (v interface{}, found bool) {
+	env.rangeObject(m, func(key string, elem interface{}) bool {
+		if strings.EqualFold(key, s.field) {
+			v, found = elem, true
+		}
+		return !found
+	})
+	return v, found
+}
+
+// foldMap is like foldObject for maps keyed by strings or interfaces.
+func (s *SingleField) foldMap(env *Env, value reflect.Value) (v reflect.Value, found bool) {
+	env.rangeMap(value, func(key, elem reflect.Value) bool {
+		if key.Kind() == reflect.Interface {
+			key = key.Elem()
 		}
-		switch t {
-		case reflect.Int:
-			key = reflect.ValueOf(int(i))
-		case reflect.Int8:
-			key = reflect.ValueOf(int8(i))
-		case reflect.Int16:
-			key = reflect.ValueOf(int16(i))
-		case reflect.Int32:
-			key = reflect.ValueOf(int32(i))
-		case reflect.Int64:
-			key = reflect.ValueOf(i)
+		if key.Kind() == reflect.String && strings.EqualFold(key.String(), s.field) {
+			v, found = elem, true
 		}
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		u, err := strconv.ParseUint(s.field, 10, 64)
+		return !found
+	})
+	return v, found
+}
+
+func (s *SingleField) getMap(env *Env, value reflect.Value) (*Result, error) {
+	kt := value.Type().Key()
+	if kt.Kind() != reflect.String && kt.Implements(textMarshalerType) {
+		return s.getTextMap(env, value)
+	}
+	if kt.Kind() == reflect.Interface {
+		return s.getInterfaceMap(env, value)
+	}
+	key, ok, err := parseKey(s.field, kt)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.errNotFound()
+	}
+	v := value.MapIndex(key)
+	if !v.IsValid() && kt.Kind() == reflect.String && env.opts.CaseInsensitive {
+		v, _ = s.foldMap(env, value)
+	}
+	if !v.IsValid() {
+		return nil, s.errNotFound()
+	}
+	return s.next.Get(env, v.Interface())
+}
+
+// getInterfaceMap looks the field up as a string, then as each numeric type a decoder commonly
+// produces for keys, in maps such as map[interface{}]interface{}.
+func (s *SingleField) getInterfaceMap(env *Env, value reflect.Value) (*Result, error) {
+	kt := value.Type().Key()
+	for _, t := range interfaceKeyTypes {
+		key, ok, _ := parseKey(s.field, t)
+		if !ok || !t.AssignableTo(kt) {
+			continue
+		}
+		if v := value.MapIndex(key); v.IsValid() {
+			return s.next.Get(env, v.Interface())
+		}
+	}
+	if env.opts.CaseInsensitive {
+		if v, ok := s.foldMap(env, value); ok {
+			return s.next.Get(env, v.Interface())
+		}
+	}
+	return nil, s.errNotFound()
+}
+
+var interfaceKeyTypes = []reflect.Type{
+	reflect.TypeOf(""),
+	reflect.TypeOf(int(0)),
+	reflect.TypeOf(int64(0)),
+	reflect.TypeOf(uint64(0)),
+	reflect.TypeOf(float64(0)),
+}
+
+// parseKey converts field into a value of the map key type t. It reports false
+// if field is not a valid t, and an error if t can not be parsed from a string.
+func parseKey(field string, t reflect.Type) (reflect.Value, bool, error) {
+	key := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(field, 10, t.Bits())
 		if err != nil {
-			return nil, s.errNotFound()
+			return key, false, nil
 		}
-		switch t {
-		case reflect.Uint:
-			key = reflect.ValueOf(uint(u))
-		case reflect.Uint8:
-			key = reflect.ValueOf(uint8(u))
-		case reflect.Uint16:
-			key = reflect.ValueOf(uint16(u))
-		case reflect.Uint32:
-			key = reflect.ValueOf(uint32(u))
-		case reflect.Uint64:
-			key = reflect.ValueOf(u)
+		key.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(field, 10, t.Bits())
+		if err != nil {
+			return key, false, nil
 		}
+		key.SetUint(u)
 	case reflect.Float32, reflect.Float64:
-		float, err := strconv.ParseFloat(s.field, 64)
+		f, err := strconv.ParseFloat(field, t.Bits())
 		if err != nil {
-			return nil, s.errNotFound()
-		}
-		switch t {
-		case reflect.Float32:
-			key = reflect.ValueOf(float32(float))
-		case reflect.Float64:
-			key = reflect.ValueOf(float)
+			return key, false, nil
 		}
+		key.SetFloat(f)
 	case reflect.Bool:
-		b, err := strconv.ParseBool(s.field)
+		b, err := strconv.ParseBool(field)
 		if err != nil {
-			return nil, s.errNotFound()
+			return key, false, nil
 		}
-		key = reflect.ValueOf(b)
+		key.SetBool(b)
 	case reflect.String:
+		key.SetString(field)
 	default:
-		return nil, fmt.Errorf("unsupported map where key type is %s", t)
-	}
-	v := value.MapIndex(key)
-	if !v.IsValid() {
-		return nil, s.errNotFound()
+		return key, false, fmt.Errorf("unsupported map where key type is %s", t)
 	}
-	return s.next.Get(v.Interface())
+	return key, true, nil
 }
 
-func (s *SingleField) getStruct(value reflect.Value) (*Result, error) {
-	for i := 0; i < value.NumField(); i++ {
-		key, omitempty := getFieldKey(value.Type().Field(i))
-		if key != s.field {
-			continue
+func (s *SingleField) getTextMap(env *Env, value reflect.Value) (*Result, error) {
+	iter := value.MapRange()
+	for iter.Next() {
+		if mapKeyString(iter.Key()) == s.field {
+			return s.next.Get(env, iter.Value().Interface())
 		}
-		if omitempty && value.IsZero() {
-			break
-		}
-		return s.next.Get(value.Field(i).Interface())
 	}
 	return nil, s.errNotFound()
 }
+
+func (s *SingleField) getStruct(env *Env, value reflect.Value) (*Result, error) {
+	f, ok := env.typeFields(value.Type()).lookup(s.field, env.opts.CaseInsensitive)
+	if !ok || !env.fieldVisible(f) {
+		return nil, s.errNotFound()
+	}
+	v, ok := f.value(value)
+	if !ok {
+		return nil, s.errNotFound()
+	}
+	return s.next.Get(env, v.Interface())
+}
@@ -7,13 +7,13 @@ import (
 )
 
 type Slice struct {
-	start int
-	end   int
-	step  int
+	start *int
+	end   *int
+	step  *int
 	next  Node
 }
 
-func NewSlice(start, end, step int, next Node) *Slice {
+func NewSlice(start, end, step *int, next Node) *Slice {
 	return &Slice{
 		start: start,
 		end:   end,
@@ -24,20 +24,20 @@ func NewSlice(start, end, step int, next Node) *Slice {
 
 func (s *Slice) String() string {
 	start, end, step := "", "", ""
-	if s.start != 0 {
-		start = strconv.Itoa(s.start)
+	if s.start != nil {
+		start = strconv.Itoa(*s.start)
 	}
-	if s.end != 0 {
-		end = strconv.Itoa(s.end)
+	if s.end != nil {
+		end = strconv.Itoa(*s.end)
 	}
-	if s.step != 0 {
-		step = strconv.Itoa(s.step)
+	if s.step != nil {
+		step = strconv.Itoa(*s.step)
 	}
 	return fmt.Sprintf("[%s:%s:%s]%s", start, end, step, s.next.String())
 }
 
-func (s *Slice) Get(data interface{}) (*Result, error) {
-	r, err := s.get(data)
+func (s *Slice) Get(env *Env, data interface{}) (*Result, error) {
+	r, err := s.get(env, data)
 	if err != nil {
 		return nil, err
 	}
@@ -47,35 +47,111 @@ func (s *Slice) Get(data interface{}) (*Result, error) {
 	}, nil
 }
 
-func (s *Slice) get(data interface{}) ([]interface{}, error) {
-	value := reflect.ValueOf(data)
-	if value.Kind() != reflect.Array && value.Kind() != reflect.Slice {
-		return nil, fmt.Errorf("can not get slice without array")
+func (s *Slice) get(env *Env, data interface{}) ([]interface{}, error) {
+	if err := env.visit(); err != nil {
+		return nil, err
 	}
-	start := (s.start + value.Len()) % value.Len()
-	if start < 0 {
-		start = 0
+	data, err := env.resolve(data)
+	if err != nil {
+		return nil, err
 	}
-	end := (s.end + value.Len()) % value.Len()
-	if end < 0 {
-		return nil, fmt.Errorf("end index out of bounds: [%d]", s.end)
-	} else if end == 0 {
-		end = value.Len()
+	if a, ok := data.([]interface{}); ok {
+		return s.getArray(env, len(a), func(i int) interface{} {
+			return a[i]
+		})
 	}
-	if start >= end {
-		return nil, fmt.Errorf("not found")
+	if a, ok := lookupAdapter(data); ok {
+		if a.Kind(data) != KindArray {
+			return nil, notFound("can not get slice without array")
+		}
+		// The elements are collected by one Range, as Index may have to scan the array from its start.
+		elems := make([]interface{}, 0)
+		end, bounded := s.knownEnd()
+		a.Range(data, func(_ interface{}, v interface{}) bool {
+			if bounded && len(elems) >= end {
+				return false
+			}
+			elems = append(elems, v)
+			return true
+		})
+		return s.getArray(env, len(elems), func(i int) interface{} {
+			return elems[i]
+		})
 	}
-	step := s.step
-	if step == 0 {
-		step = 1
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, notFound("can not get slice from nil")
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Array && value.Kind() != reflect.Slice {
+		return nil, notFound("can not get slice without array")
 	}
-	result := make([]interface{}, 0, (end-start)/step)
-	for i := start; i < value.Len() && i < end; i += step {
-		r, err := s.next.Get(value.Index(i).Interface())
-		if err != nil {
-			continue
+	return s.getArray(env, value.Len(), func(i int) interface{} {
+		return value.Index(i).Interface()
+	})
+}
+
+func (s *Slice) getArray(env *Env, n int, index func(int) interface{}) ([]interface{}, error) {
+	lower, upper, step := s.bounds(n)
+	result := make([]interface{}, 0)
+	for i := lower; (step > 0 && i < upper) || (step < 0 && i > upper); {
+		r, err := s.next.Get(env, index(i))
+		if err != nil && env.Err() != nil {
+			return nil, err
+		}
+		if err == nil {
+			if r.multi {
+				result = append(result, r.data.([]interface{})...)
+			} else {
+				result = append(result, r.data)
+			}
 		}
-		result = append(result, r.data)
+		if (step > 0 && step >= upper-i) || (step < 0 && step <= upper-i) {
+			break
+		}
+		i += step
 	}
 	return result, nil
 }
+
+// knownEnd returns the index the slice ends before whatever the length of the array,
+// if it does not count indexes from the end of the array.
+func (s *Slice) knownEnd() (int, bool) {
+	if s.step != nil && *s.step <= 0 || s.start != nil && *s.start < 0 || s.end == nil || *s.end < 0 {
+		return 0, false
+	}
+	return *s.end, true
+}
+
+// bounds returns the first index, the exclusive last index and the step of the slice
+// over an array of length n, following RFC 9535. A zero step selects nothing.
+func (s *Slice) bounds(n int) (int, int, int) {
+	step := 1
+	if s.step != nil {
+		step = *s.step
+	}
+	switch {
+	case step > 0:
+		lower, upper := 0, n
+		if s.start != nil {
+			lower = clamp(normalizeIndex(*s.start, n), 0, n)
+		}
+		if s.end != nil {
+			upper = clamp(normalizeIndex(*s.end, n), 0, n)
+		}
+		return lower, upper, step
+	case step < 0:
+		lower, upper := n-1, -1
+		if s.start != nil {
+			lower = clamp(normalizeIndex(*s.start, n), -1, n-1)
+		}
+		if s.end != nil {
+			upper = clamp(normalizeIndex(*s.end, n), -1, n-1)
+		}
+		return lower, upper, step
+	default:
+		return 0, 0, 0
+	}
+}
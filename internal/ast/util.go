@@ -1,28 +1,20 @@
 package ast
 
-import (
-	"reflect"
-	"strings"
-)
-
-func getFieldKey(sf reflect.StructField) (key string, omitempty bool) {
-	if !sf.IsExported() {
-		return "", false
+// normalizeIndex converts a negative index counted from the end of an array of length n
+// into an index counted from the start. The result may still be out of range.
+func normalizeIndex(i, n int) int {
+	if i < 0 {
+		return n + i
 	}
-	key = sf.Name
-	tag, ok := sf.Tag.Lookup("json")
-	if !ok {
-		return key, false
+	return i
+}
+
+func clamp(i, min, max int) int {
+	if i < min {
+		return min
 	}
-	for i, s := range strings.Split(tag, ",") {
-		if i == 0 {
-			key = s
-			continue
-		}
-		if s == "omitempty" {
-			omitempty = true
-			break
-		}
+	if i > max {
+		return max
 	}
-	return key, omitempty
+	return i
 }
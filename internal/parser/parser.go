@@ -32,7 +32,7 @@ var stateMachine = map[tokenType][]tokenType{
 	TokenDot:       {TokenAll, TokenField, TokenSquare},
 	TokenRecursion: {TokenAll, TokenField, TokenSquare},
 	TokenField:     {TokenDot, TokenRecursion, TokenSquare},
-	TokenSquare:    {TokenDot, TokenRecursion},
+	TokenSquare:    {TokenDot, TokenRecursion, TokenSquare},
 }
 
 func transfer(from, to tokenType) bool {
@@ -229,10 +229,10 @@ func (p *Parser) parseFields() (ast.Node, error) {
 			return nil, err
 		}
 		fields = append(fields, str)
+		p.skipSpace()
 		if p.offset == len(p.input) {
 			return nil, fmt.Errorf("syntax err near %s: could not found ]", string(p.input[p.offset:]))
 		}
-		p.skipSpace()
 		if p.input[p.offset] == rightSquareBracket {
 			p.offset++
 			break
@@ -254,14 +254,19 @@ func (p *Parser) parseFields() (ast.Node, error) {
 }
 
 func (p *Parser) scanString() (string, error) {
-	if p.input[p.offset] != singleQuotes && p.input[p.offset] != doubleQuotes {
+	if p.offset == len(p.input) || p.input[p.offset] != singleQuotes && p.input[p.offset] != doubleQuotes {
 		return "", fmt.Errorf(`syntax error near %q: could not find quotes`, string(p.input[p.offset:]))
 	}
 	result := make([]rune, 0)
 	i := p.offset + 1
 	for ; i < len(p.input) && p.input[i] != p.input[p.offset]; i++ {
-		if p.input[i] == '\\' {
+		if p.input[i] == '\\' && i+1 < len(p.input) {
 			i++
+			if r, n, ok := unescape(p.input[i:]); ok {
+				result = append(result, r)
+				i += n - 1
+				continue
+			}
 		}
 		result = append(result, p.input[i])
 	}
@@ -272,42 +277,75 @@ func (p *Parser) scanString() (string, error) {
 	return string(result), nil
 }
 
+// unescape decodes the escape sequence after a backslash at the start of s,
+// and reports the number of runes it takes.
+func unescape(s []rune) (rune, int, bool) {
+	switch s[0] {
+	case 'b':
+		return '\b', 1, true
+	case 'f':
+		return '\f', 1, true
+	case 'n':
+		return '\n', 1, true
+	case 'r':
+		return '\r', 1, true
+	case 't':
+		return '\t', 1, true
+	case 'u':
+		if len(s) < 5 {
+			return 0, 0, false
+		}
+		r, err := strconv.ParseUint(string(s[1:5]), 16, 32)
+		if err != nil {
+			return 0, 0, false
+		}
+		return rune(r), 5, true
+	}
+	return 0, 0, false
+}
+
 type indexesData struct {
 	isSlice bool
 	index   int
-	start   int
-	end     int
-	step    int
+	start   *int
+	end     *int
+	step    *int
 }
 
 func (p *Parser) parseIndexes() (ast.Node, error) {
 	data := make([]*indexesData, 0, 1)
-	for slice := make([]int, 0, 3); ; {
+	for slice := make([]*int, 0, 3); ; {
 		p.skipSpace()
-		integer := 0
+		if p.offset == len(p.input) {
+			return nil, fmt.Errorf("syntax err near %q : could not found ]", string(p.input[p.offset:]))
+		}
+		var integer *int
 		if !strings.ContainsRune(":,]", p.input[p.offset]) {
-			var err error
-			integer, err = p.scanInteger()
+			i, err := p.scanInteger()
 			if err != nil {
 				return nil, err
 			}
+			integer = &i
 		}
 		if len(slice) == 3 {
 			return nil, fmt.Errorf(`syntax error near %q`, string(p.input[p.offset:]))
 		}
 		slice = append(slice, integer)
+		p.skipSpace()
 		if p.offset == len(p.input) {
 			return nil, fmt.Errorf("syntax err near %q : could not found ]", string(p.input[p.offset:]))
 		}
-		p.skipSpace()
 		p.offset++
 		switch p.input[p.offset-1] {
 		case colon:
 		case comma, rightSquareBracket:
 			if len(slice) == 1 {
-				data = append(data, &indexesData{isSlice: false, index: slice[0]})
+				if slice[0] == nil {
+					return nil, fmt.Errorf(`syntax error near %q: expected integer`, string(p.input[p.offset-1:]))
+				}
+				data = append(data, &indexesData{isSlice: false, index: *slice[0]})
 			} else {
-				end, step := 0, 0
+				var end, step *int
 				if len(slice) > 1 {
 					end = slice[1]
 					if len(slice) > 2 {
@@ -321,7 +359,7 @@ func (p *Parser) parseIndexes() (ast.Node, error) {
 				goto exit
 			}
 		default:
-			return nil, fmt.Errorf("syntax err near %s", string(p.input[p.offset:]))
+			return nil, fmt.Errorf("syntax err near %s", string(p.input[p.offset-1:]))
 		}
 	}
 exit:
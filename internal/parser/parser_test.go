@@ -17,10 +17,13 @@ func TestParser_Parse(t *testing.T) {
 		{`$.[::2]`, false, `$[::2]`},
 		{`$.[:,2]`, false, `$[::,2]`},
 		{`$.[:,2,:]`, false, `$[::,2,::]`},
-		{`$.a[1:5:3,7,8].a`, false, `$["a"][1:5:3,7,8]`},
+		{`$.a[1:5:3,7,8].a`, false, `$["a"][1:5:3,7,8]["a"]`},
 		{`$.a.b.c`, false, `$["a"]["b"]["c"]`},
 		{`$. $a`, false, `$[" $a"]`},
 		{`$.['a\'a', "b\"b"]`, false, `$["a'a","b\"b"]`},
+		{`$['a'][0]["b"][1:]`, false, `$["a"][0]["b"][1::]`},
+		{`$["a"]["b"]["c"]`, false, `$["a"]["b"]["c"]`},
+		{`$['a\n\u00e9\t']`, false, `$["a\né\t"]`},
 
 		{`$....a`, true, ``},
 		{`$[1`, true, ``},
@@ -2,47 +2,102 @@ package jsonpath
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/xianlianghe0123/jsonpath/internal/ast"
 	"github.com/xianlianghe0123/jsonpath/internal/parser"
 	"unsafe"
 )
 
+// ErrNotFound is matched by errors.Is for the errors of paths that select nothing.
+// A member holding null is found and returned as nil.
+var ErrNotFound = ast.ErrNotFound
+
 type Compiled struct {
-	a *ast.AST
+	a    *ast.AST
+	opts ast.Options
 }
 
-func Compile(jsonPath string) (*Compiled, error) {
+func Compile(jsonPath string, opts ...Option) (*Compiled, error) {
 	a, err := parser.NewParser(jsonPath).Parse()
 	if err != nil {
 		return nil, err
 	}
-	return &Compiled{
+	c := &Compiled{
 		a: a,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(&c.opts)
+	}
+	return c, nil
 }
 
-func MustCompile(jsonPath string) *Compiled {
-	c, err := Compile(jsonPath)
+func MustCompile(jsonPath string, opts ...Option) *Compiled {
+	c, err := Compile(jsonPath, opts...)
 	if err != nil {
 		panic(err)
 	}
 	return c
 }
 
+// String returns the normalized form of the compiled path.
+func (c *Compiled) String() string {
+	return c.a.String()
+}
+
 func (c *Compiled) Get(data interface{}) (interface{}, error) {
-	return c.a.Get(data)
+	return c.a.Get(ast.NewEnv(&c.opts), data)
+}
+
+// Exists reports whether the path selects at least one value of data, null included.
+func (c *Compiled) Exists(data interface{}) (bool, error) {
+	return c.a.Exists(ast.NewEnv(&c.opts), data)
+}
+
+// GetContext evaluates like Get, but aborts with ctx.Err() once ctx is done
+// and with a *LimitError once a budget of limits is exceeded.
+func (c *Compiled) GetContext(ctx context.Context, data interface{}, limits Limits) (interface{}, error) {
+	env := ast.NewEnv(&c.opts)
+	env.SetContext(ctx)
+	env.SetLimits(limits)
+	return c.a.Get(env, data)
 }
 
 func (c *Compiled) GetBytes(dataBytes []byte) (interface{}, error) {
-	var data interface{}
+	data, env, err := decode(&c.opts, dataBytes)
+	if err != nil {
+		return nil, err
+	}
+	return c.a.Get(env, data)
+}
+
+// decode decodes dataBytes for GetBytes, and returns the Env to evaluate paths with opts.
+func decode(opts *ast.Options, dataBytes []byte) (interface{}, *ast.Env, error) {
+	data, env, _, err := decodePrefix(opts, dataBytes)
+	return data, env, err
+}
+
+// decodePrefix is like decode, but also returns the offset the first value of dataBytes ends at.
+func decodePrefix(opts *ast.Options, dataBytes []byte) (interface{}, *ast.Env, int, error) {
 	d := json.NewDecoder(bytes.NewReader(dataBytes))
 	d.UseNumber()
-	err := d.Decode(&data)
+	env := ast.NewEnv(opts)
+	var data interface{}
+	if opts.MapOrder == MapOrderDocument {
+		v, keyOrder, err := decodeOrdered(d)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		env.SetKeyOrder(keyOrder)
+		data = v
+	} else if err := d.Decode(&data); err != nil {
+		return nil, nil, 0, err
+	}
+	data, err := convertNumbers(data, opts.Numbers)
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
-	return c.Get(data)
+	return data, env, int(d.InputOffset()), nil
 }
 
 func (c *Compiled) GetString(dataStr string) (interface{}, error) {
@@ -50,7 +105,7 @@ func (c *Compiled) GetString(dataStr string) (interface{}, error) {
 }
 
 func Get(jsonPath string, data interface{}) (interface{}, error) {
-	c, err := Compile(jsonPath)
+	c, err := compileCached(jsonPath)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +113,7 @@ func Get(jsonPath string, data interface{}) (interface{}, error) {
 }
 
 func GetBytes(jsonPath string, dataBytes []byte) (interface{}, error) {
-	c, err := Compile(jsonPath)
+	c, err := compileCached(jsonPath)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +121,7 @@ func GetBytes(jsonPath string, dataBytes []byte) (interface{}, error) {
 }
 
 func GetString(jsonPath string, dataStr string) (interface{}, error) {
-	c, err := Compile(jsonPath)
+	c, err := compileCached(jsonPath)
 	if err != nil {
 		return nil, err
 	}
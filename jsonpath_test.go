@@ -2,11 +2,20 @@ package jsonpath
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
+	"math/big"
+	"net/netip"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type Book struct {
@@ -69,15 +78,15 @@ func TestGet(t *testing.T) {
 		{`$.store.book[*].author`, `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
 		{`$.store.book[*].['author',"price"]`, `["Nigel Rees",8.95,"Evelyn Waugh",12.99,"Herman Melville",8.99,"J. R. R. Tolkien",22.99]`},
 		{`$..author`, `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
-		{`$.store.*`, `[[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}],{"color":"red","price":19.95}]`},
-		{"$.store..price", `[8.95,12.99,8.99,22.99,19.95]`},
+		{`$.store.*`, `[{"color":"red","price":19.95},[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]]`},
+		{"$.store..price", `[19.95,8.95,12.99,8.99,22.99]`},
 		{`$..book[2]`, `[{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99}]`},
 		{`$..book[-1:]`, `[{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]`},
 		{`$..book[0,1]`, `[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99}]`},
 		{`$..book[:2]`, `[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99}]`},
 		{`$..book[:2,3]`, `[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]`},
-		{`$..`, `[{"store":{"book":[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}],"bicycle":{"color":"red","price":19.95}}},{"book":[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}],"bicycle":{"color":"red","price":19.95}},[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}],{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99},{"color":"red","price":19.95}]`},
-		{`$..*`, `[{"book":[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}],"bicycle":{"color":"red","price":19.95}},[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}],{"color":"red","price":19.95},{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99},"reference","Nigel Rees","Sayings of the Century",8.95,"fiction","Evelyn Waugh","Sword of Honour",12.99,"fiction","Herman Melville","Moby Dick","0-553-21311-3",8.99,"fiction","J. R. R. Tolkien","The Lord of the Rings","0-395-19395-8",22.99,"red",19.95]`},
+		{`$..`, `[{"store":{"bicycle":{"color":"red","price":19.95},"book":[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]}},{"bicycle":{"color":"red","price":19.95},"book":[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]},{"color":"red","price":19.95},[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}],{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]`},
+		{`$..*`, `[{"bicycle":{"color":"red","price":19.95},"book":[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]},{"color":"red","price":19.95},[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}],"red",19.95,{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99},"reference","Nigel Rees","Sayings of the Century",8.95,"fiction","Evelyn Waugh","Sword of Honour",12.99,"fiction","Herman Melville","Moby Dick","0-553-21311-3",8.99,"fiction","J. R. R. Tolkien","The Lord of the Rings","0-395-19395-8",22.99]`},
 	}
 	for _, c := range cases {
 		d, err := Get(c.jsonPath, data)
@@ -96,7 +105,667 @@ func TestGet(t *testing.T) {
 	}
 }
 
-func BenchmarkGet(b *testing.B) {
+func TestMapOrder(t *testing.T) {
+	doc := `{"z":{"c":3,"a":1,"b":2},"y":[{"k2":"v2","k1":"v1"}],"x":0}`
+	cases := []struct {
+		jsonPath    string
+		order       MapOrder
+		expectation string
+	}{
+		{`$.*`, MapOrderSorted, `[0,[{"k2":"v2","k1":"v1"}],{"c":3,"a":1,"b":2}]`},
+		{`$..*`, MapOrderSorted, `[0,[{"k2":"v2","k1":"v1"}],{"c":3,"a":1,"b":2},{"k2":"v2","k1":"v1"},"v1","v2",1,2,3]`},
+		{`$.*`, MapOrderDocument, `[{"c":3,"a":1,"b":2},[{"k2":"v2","k1":"v1"}],0]`},
+		{`$..*`, MapOrderDocument, `[{"c":3,"a":1,"b":2},[{"k2":"v2","k1":"v1"}],0,3,1,2,{"k2":"v2","k1":"v1"},"v2","v1"]`},
+		{`$.z[*]`, MapOrderDocument, `[3,1,2]`},
+	}
+	for _, c := range cases {
+		d, err := MustCompile(c.jsonPath, WithMapOrder(c.order)).GetString(doc)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			continue
+		}
+		b, _ := json.Marshal(d)
+		var cur, e interface{}
+		json.Unmarshal(b, &cur)
+		json.Unmarshal([]byte(c.expectation), &e)
+		if !reflect.DeepEqual(cur, e) {
+			t.Errorf("Case %q order %d, current:%s, expectation:%s\n", c.jsonPath, c.order, string(b), c.expectation)
+		}
+	}
+}
+
+func TestIndexBounds(t *testing.T) {
+	doc := `[0,1,2]`
+	cases := []struct {
+		jsonPath    string
+		hasErr      bool
+		expectation string
+	}{
+		{`$[0]`, false, `0`},
+		{`$[-1]`, false, `2`},
+		{`$[-3]`, false, `0`},
+		{`$[3]`, true, ``},
+		{`$[5]`, true, ``},
+		{`$[-4]`, true, ``},
+		{`$[:]`, false, `[0,1,2]`},
+		{`$[1:]`, false, `[1,2]`},
+		{`$[:0]`, false, `[]`},
+		{`$[-2:]`, false, `[1,2]`},
+		{`$[-10:10]`, false, `[0,1,2]`},
+		{`$[::2]`, false, `[0,2]`},
+		{`$[::-1]`, false, `[2,1,0]`},
+		{`$[2:0:-1]`, false, `[2,1]`},
+		{`$[::0]`, false, `[]`},
+		{`$[1::9223372036854775807]`, false, `[1]`},
+		{`$[0,5,-1]`, false, `[0,2]`},
+	}
+	for _, c := range cases {
+		d, err := GetString(c.jsonPath, doc)
+		if err != nil {
+			if !c.hasErr {
+				t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			}
+			continue
+		}
+		if c.hasErr {
+			t.Errorf("Case %q expected error, current:%v", c.jsonPath, d)
+			continue
+		}
+		b, _ := json.Marshal(d)
+		if string(b) != c.expectation {
+			t.Errorf("Case %q, current:%s, expectation:%s\n", c.jsonPath, string(b), c.expectation)
+		}
+	}
+	for _, p := range []string{`$[0]`, `$[-1]`} {
+		if _, err := GetString(p, `[]`); err == nil {
+			t.Errorf("Case %q on empty array expected error", p)
+		}
+	}
+}
+
+func TestExists(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"a":null,"b":{"c":[null,1]},"e":[]}`), &doc)
+	cases := []struct {
+		jsonPath    string
+		expectation bool
+	}{
+		{`$`, true},
+		{`$.a`, true},
+		{`$.x`, false},
+		{`$.a.x`, false},
+		{`$.b.c[0]`, true},
+		{`$.b.c[2]`, false},
+		{`$.b.c[0].x`, false},
+		{`$['a','x']`, true},
+		{`$['x','y']`, false},
+		{`$.e[*]`, false},
+		{`$..c`, true},
+		{`$..x`, false},
+	}
+	for _, c := range cases {
+		ok, err := MustCompile(c.jsonPath).Exists(doc)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			continue
+		}
+		if ok != c.expectation {
+			t.Errorf("Case %q, current:%v, expectation:%v", c.jsonPath, ok, c.expectation)
+		}
+	}
+	if d, err := Get(`$.a`, doc); err != nil || d != nil {
+		t.Errorf("null member should be found, current:%v, err:%v", d, err)
+	}
+	if _, err := Get(`$.b.x`, doc); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing member should be ErrNotFound, current:%v", err)
+	}
+	if _, err := MustCompile(`$..c`).GetContext(context.Background(), doc, Limits{MaxVisited: 1}); errors.Is(err, ErrNotFound) {
+		t.Errorf("limit errors should not be ErrNotFound, current:%v", err)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	doc := `{"n":[1,-2.5,1e2,12345678901234567890,0.1]}`
+	cases := []struct {
+		mode        NumberMode
+		order       MapOrder
+		expectation string
+	}{
+		{NumberJSON, MapOrderSorted, `[json.Number(1) json.Number(-2.5) json.Number(1e2) json.Number(12345678901234567890) json.Number(0.1)]`},
+		{NumberFloat64, MapOrderSorted, `[float64(1) float64(-2.5) float64(100) float64(1.2345678901234567e+19) float64(0.1)]`},
+		{NumberInt64, MapOrderSorted, `[int64(1) float64(-2.5) float64(100) float64(1.2345678901234567e+19) float64(0.1)]`},
+		{NumberInt64, MapOrderDocument, `[int64(1) float64(-2.5) float64(100) float64(1.2345678901234567e+19) float64(0.1)]`},
+		{NumberBig, MapOrderSorted, `[*big.Int(1) *big.Float(-2.5) *big.Float(100) *big.Int(12345678901234567890) *big.Float(0.1)]`},
+	}
+	for _, c := range cases {
+		d, err := MustCompile(`$.n`, WithNumbers(c.mode), WithMapOrder(c.order)).GetString(doc)
+		if err != nil {
+			t.Errorf("Case %d err: %+v", c.mode, err)
+			continue
+		}
+		cur := make([]string, 0)
+		for _, n := range d.([]interface{}) {
+			cur = append(cur, fmt.Sprintf("%T(%v)", n, n))
+		}
+		if s := "[" + strings.Join(cur, " ") + "]"; s != c.expectation {
+			t.Errorf("Case %d, current:%s, expectation:%s", c.mode, s, c.expectation)
+		}
+	}
+	if _, err := MustCompile(`$`, WithNumbers(NumberFloat64)).GetString(`1e999`); err == nil {
+		t.Errorf("out of range float64 expected error")
+	}
+}
+
+func TestGetContext(t *testing.T) {
+	cases := []struct {
+		jsonPath string
+		limits   Limits
+		kind     LimitKind
+	}{
+		{`$..*`, Limits{}, 0},
+		{`$..author`, Limits{MaxResults: 4}, 0},
+		{`$..author`, Limits{MaxResults: 3}, LimitResults},
+		{`$..*`, Limits{MaxDepth: 3}, 0},
+		{`$..*`, Limits{MaxDepth: 2}, LimitDepth},
+		{`$.store.book[0].author`, Limits{MaxVisited: 4}, 0},
+		{`$..*`, Limits{MaxVisited: 10}, LimitVisited},
+	}
+	for _, c := range cases {
+		_, err := MustCompile(c.jsonPath).GetContext(context.Background(), data, c.limits)
+		var le *LimitError
+		switch {
+		case c.kind == 0 && err != nil:
+			t.Errorf("Case %q %+v err: %+v", c.jsonPath, c.limits, err)
+		case c.kind != 0 && (!errors.As(err, &le) || le.Kind != c.kind):
+			t.Errorf("Case %q %+v expected %s error, current:%v", c.jsonPath, c.limits, c.kind, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := MustCompile(`$..*`).GetContext(ctx, data, Limits{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, current:%v", err)
+	}
+}
+
+type Tree struct {
+	Name     string
+	Parent   *Tree
+	Children []*Tree
+}
+
+func TestParallel(t *testing.T) {
+	var doc interface{}
+	items := make([]interface{}, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		items = append(items, map[string]interface{}{"id": i, "tags": []interface{}{i, map[string]interface{}{"id": -i}}})
+	}
+	doc = map[string]interface{}{"items": items, "typed": []Book{{Title: "a"}, {Title: "b"}, {Title: "c"}}}
+	for _, p := range []string{`$..id`, `$..*`, `$..title`, `$..tags[1].id`} {
+		e, err := Get(p, doc)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", p, err)
+			continue
+		}
+		for _, workers := range []int{2, 3, 8} {
+			d, err := MustCompile(p, WithParallel(workers, 2)).Get(doc)
+			if err != nil {
+				t.Errorf("Case %q workers %d err: %+v", p, workers, err)
+				continue
+			}
+			if !reflect.DeepEqual(d, e) {
+				t.Errorf("Case %q workers %d results differ from sequential evaluation", p, workers)
+			}
+		}
+	}
+
+	c := MustCompile(`$..id`, WithParallel(4, 2))
+	_, err := c.GetContext(context.Background(), doc, Limits{MaxResults: 1500})
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Kind != LimitResults || limitErr.Limit != 1500 {
+		t.Errorf("summed results should exceed the limit, current:%v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetContext(ctx, doc, Limits{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled context expected error, current:%v", err)
+	}
+	tree := &Tree{Name: "root"}
+	tree.Children = []*Tree{{Name: "a"}, tree}
+	if _, err := MustCompile(`$..Name`, WithParallel(2, 2)).Get(tree); !errors.As(err, new(*CycleError)) {
+		t.Errorf("cycles below a parallel array expected error, current:%v", err)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	root := &Tree{Name: "root"}
+	root.Children = []*Tree{{Name: "child", Parent: root}}
+	self := map[string]interface{}{"a": 1}
+	self["self"] = self
+	list := []interface{}{"a", nil}
+	list[1] = list
+	registerAdapters()
+	syncMap := &testSyncMap{}
+	syncMap.Store("x", 1)
+	syncMap.Store("self", syncMap)
+
+	cases := []struct {
+		jsonPath    string
+		data        interface{}
+		expectation []interface{}
+	}{
+		{`$..Name`, root, []interface{}{"root", "child"}},
+		{`$..a`, self, []interface{}{1}},
+		{`$..[0]`, list, []interface{}{"a"}},
+		{`$..x`, syncMap, []interface{}{1}},
+	}
+	for _, c := range cases {
+		d, err := MustCompile(c.jsonPath, WithCyclePolicy(CycleSkip)).Get(c.data)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", c.jsonPath, err)
+		} else if !reflect.DeepEqual(d, c.expectation) {
+			t.Errorf("Case %q, current:%v, expectation:%v\n", c.jsonPath, d, c.expectation)
+		}
+		var ce *CycleError
+		if _, err := MustCompile(c.jsonPath).Get(c.data); !errors.As(err, &ce) {
+			t.Errorf("Case %q expected cycle error, current:%v", c.jsonPath, err)
+		}
+	}
+
+	shared := &Tree{Name: "shared"}
+	dag := []*Tree{shared, shared}
+	if d, err := Get(`$..Name`, dag); err != nil || len(d.([]interface{})) != 2 {
+		t.Errorf("shared pointers are not cycles, current:%v, err:%v", d, err)
+	}
+}
+
+func TestStructFields(t *testing.T) {
+	type inner struct {
+		Name string `json:"name"`
+		note string
+	}
+	v := &inner{Name: "n", note: "hidden"}
+	cases := []struct {
+		jsonPath    string
+		expectation string
+	}{
+		{`$.name`, `"n"`},
+		{`$.*`, `["n"]`},
+		{`$..*`, `["n"]`},
+		{`$.note`, `null`},
+	}
+	for _, c := range cases {
+		d, _ := Get(c.jsonPath, v)
+		b, _ := json.Marshal(d)
+		if string(b) != c.expectation {
+			t.Errorf("Case %q, current:%s, expectation:%s\n", c.jsonPath, string(b), c.expectation)
+		}
+	}
+}
+
+type Base struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Shadow  string
+	Ignored string `json:"-"`
+	Dash    string `json:"-,"`
+}
+
+type Audit struct {
+	Name    string
+	Created int64 `json:"created,string"`
+}
+
+type base struct {
+	Hidden  string `json:"hidden"`
+	Visible string
+}
+
+type Account struct {
+	Base
+	*Audit
+	base
+	Shadow   string
+	Untagged string  `json:",omitempty"`
+	Price    float64 `json:"price,string"`
+	Quoted   string  `json:"quoted,string"`
+	Ptr      *int    `json:"ptr,string"`
+	Items    []int   `json:"items,omitempty"`
+	Nested   Base    `json:"nested,omitempty"`
+	Zero     Base    `json:"zero,omitzero"`
+}
+
+func TestStructSemantics(t *testing.T) {
+	n := 7
+	values := []interface{}{
+		Account{},
+		&Account{
+			Base:     Base{ID: 1, Name: "base", Shadow: "base shadow", Ignored: "x", Dash: "dash"},
+			Audit:    &Audit{Name: "audit", Created: 1700000000},
+			base:     base{Hidden: "h", Visible: "v"},
+			Shadow:   "outer shadow",
+			Untagged: "u",
+			Price:    9.5,
+			Quoted:   `a"b`,
+			Ptr:      &n,
+			Items:    []int{1},
+			Zero:     Base{ID: 2},
+		},
+	}
+	paths := []string{
+		`$.id`, `$.name`, `$.Name`, `$.Shadow`, `$.Ignored`, `$['-']`, `$.Dash`, `$.created`,
+		`$.hidden`, `$.Visible`, `$.Untagged`, `$.price`, `$.quoted`, `$.ptr`, `$.items`,
+		`$.nested`, `$.nested.id`, `$.zero`, `$.Audit`, `$.Base`,
+	}
+	for _, v := range values {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal %+v", err)
+		}
+		for _, p := range paths {
+			d, err := Get(p, v)
+			e, eErr := GetBytes(p, b)
+			if (err == nil) != (eErr == nil) {
+				t.Errorf("Case %q on %s, err:%v, expectation err:%v", p, b, err, eErr)
+				continue
+			}
+			db, _ := json.Marshal(d)
+			eb, _ := json.Marshal(e)
+			var cur, exp interface{}
+			json.Unmarshal(db, &cur)
+			json.Unmarshal(eb, &exp)
+			if !reflect.DeepEqual(cur, exp) {
+				t.Errorf("Case %q on %s, current:%s, expectation:%s", p, b, db, eb)
+			}
+		}
+		d, _ := Get(`$.*`, v)
+		e, _ := GetBytes(`$.*`, b)
+		if len(d.([]interface{})) != len(e.([]interface{})) {
+			t.Errorf("Case $.* on %s, current:%v, expectation:%v", b, d, e)
+		}
+	}
+}
+
+type Money struct {
+	cents int64
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"amount": m.cents / 100, "currency": "EUR"})
+}
+
+type Order struct {
+	CreatedAt time.Time                  `json:"createdAt"`
+	Total     Money                      `json:"total"`
+	Count     *big.Int                   `json:"count"`
+	Hosts     map[netip.Addr]string      `json:"hosts"`
+	Missing   *time.Time                 `json:"missing"`
+	Items     []Money                    `json:"items"`
+	Nested    map[string]json.RawMessage `json:"nested"`
+}
+
+func TestMarshalers(t *testing.T) {
+	order := &Order{
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Total:     Money{cents: 1250},
+		Count:     big.NewInt(42),
+		Hosts:     map[netip.Addr]string{netip.MustParseAddr("10.0.0.2"): "b", netip.MustParseAddr("10.0.0.1"): "a"},
+		Items:     []Money{{cents: 100}, {cents: 200}},
+		Nested:    map[string]json.RawMessage{"raw": json.RawMessage(`{"x":[1,2]}`)},
+	}
+	b, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("marshal %+v", err)
+	}
+	for _, p := range []string{
+		`$.createdAt`, `$.total`, `$.total.amount`, `$.total.*`, `$.count`, `$.missing`,
+		`$.items[*].currency`, `$.items..amount`, `$.nested.raw.x[1]`, `$..x`, `$.hosts['10.0.0.1']`, `$.hosts.*`,
+	} {
+		d, err := MustCompile(p, WithMarshalers(true)).Get(order)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", p, err)
+			continue
+		}
+		e, _ := GetBytes(p, b)
+		db, _ := json.Marshal(d)
+		eb, _ := json.Marshal(e)
+		if string(db) != string(eb) {
+			t.Errorf("Case %q, current:%s, expectation:%s", p, db, eb)
+		}
+	}
+	if d, err := Get(`$.createdAt`, order); err != nil || !reflect.DeepEqual(d, order.CreatedAt) {
+		t.Errorf("values should not be marshaled by default, current:%v, err:%v", d, err)
+	}
+}
+
+type UserID string
+
+type Level int8
+
+func TestMapKeys(t *testing.T) {
+	cases := []struct {
+		jsonPath    string
+		data        interface{}
+		hasErr      bool
+		expectation string
+	}{
+		{`$.u1.name`, map[UserID]map[string]string{"u1": {"name": "a"}}, false, `"a"`},
+		{`$.u2`, map[UserID]string{"u1": "a"}, true, ``},
+		{`$.3`, map[Level]string{3: "c"}, false, `"c"`},
+		{`$.300`, map[Level]string{44: "c"}, true, ``},
+		{`$.a.1`, map[interface{}]interface{}{"a": map[interface{}]interface{}{1: "int"}}, false, `"int"`},
+		{`$.2`, map[interface{}]interface{}{int64(2): "int64"}, false, `"int64"`},
+		{`$['1.5']`, map[interface{}]interface{}{1.5: "float"}, false, `"float"`},
+		{`$.x`, map[interface{}]interface{}{1: "int"}, true, ``},
+		{`$..b`, map[interface{}]interface{}{"a": map[interface{}]interface{}{"b": true}}, false, `[true]`},
+		{`$.*`, map[float64]int{math.NaN(): 1, 2: 3}, false, `[3,1]`},
+		{`$..*`, map[float64]int{math.NaN(): 1, 2: 3}, false, `[3,1]`},
+	}
+	for _, c := range cases {
+		d, err := Get(c.jsonPath, c.data)
+		if err != nil {
+			if !c.hasErr {
+				t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			}
+			continue
+		}
+		if c.hasErr {
+			t.Errorf("Case %q expected error, current:%v", c.jsonPath, d)
+			continue
+		}
+		b, _ := json.Marshal(d)
+		if string(b) != c.expectation {
+			t.Errorf("Case %q, current:%s, expectation:%s", c.jsonPath, b, c.expectation)
+		}
+	}
+}
+
+type Optional[T any] struct {
+	value T
+	valid bool
+}
+
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, valid: true}
+}
+
+func (o Optional[T]) Unwrap() (interface{}, bool) {
+	return o.value, o.valid
+}
+
+type Profile struct {
+	Nick     sql.NullString     `json:"nick"`
+	Age      sql.NullInt64      `json:"age"`
+	Email    *sql.NullString    `json:"email"`
+	Address  Optional[*Bicycle] `json:"address"`
+	Tags     Optional[[]string] `json:"tags"`
+	Verified Optional[bool]     `json:"verified"`
+	Spouse   Optional[*Profile] `json:"spouse"`
+	Scores   []sql.NullFloat64  `json:"scores"`
+}
+
+func TestUnwrap(t *testing.T) {
+	p := &Profile{
+		Nick:    sql.NullString{String: "nick", Valid: true},
+		Address: Some(&Bicycle{Color: "red", Price: 1}),
+		Tags:    Some([]string{"a", "b"}),
+		Spouse:  Some(&Profile{Nick: sql.NullString{String: "spouse", Valid: true}}),
+		Scores:  []sql.NullFloat64{{Float64: 1.5, Valid: true}, {}},
+	}
+	cases := []struct {
+		jsonPath    string
+		expectation string
+	}{
+		{`$.nick`, `"nick"`},
+		{`$.age`, `null`},
+		{`$.email`, `null`},
+		{`$.address.color`, `"red"`},
+		{`$.tags[1]`, `"b"`},
+		{`$.verified`, `null`},
+		{`$.scores[*]`, `[1.5,null]`},
+		{`$.spouse.nick`, `"spouse"`},
+		{`$..nick`, `["nick","spouse"]`},
+		{`$.spouse.*`, `["spouse",null,null,null,null,null,null,null]`},
+	}
+	for _, c := range cases {
+		d, err := MustCompile(c.jsonPath, WithUnwrap(true)).Get(p)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			continue
+		}
+		b, _ := json.Marshal(d)
+		if string(b) != c.expectation {
+			t.Errorf("Case %q, current:%s, expectation:%s", c.jsonPath, b, c.expectation)
+		}
+	}
+	if d, _ := Get(`$.nick.String`, p); d != "nick" {
+		t.Errorf("values should not be unwrapped by default, current:%v", d)
+	}
+}
+
+type Legacy struct {
+	UserName string            `yaml:"user_name" bson:"uname"`
+	Email    string            `bson:"mail,omitempty"`
+	Skipped  string            `yaml:"-" json:"skipped"`
+	Extra    map[string]string `mapstructure:"extra"`
+}
+
+func TestTagsAndCase(t *testing.T) {
+	v := Legacy{UserName: "u", Skipped: "s", Extra: map[string]string{"Region": "eu"}}
+	cases := []struct {
+		jsonPath    string
+		opts        []Option
+		hasErr      bool
+		expectation string
+	}{
+		{`$.UserName`, nil, false, `"u"`},
+		{`$.user_name`, []Option{WithTagKeys("yaml")}, false, `"u"`},
+		{`$.skipped`, []Option{WithTagKeys("yaml")}, true, ``},
+		{`$.skipped`, []Option{WithTagKeys("json", "yaml")}, false, `"s"`},
+		{`$.uname`, []Option{WithTagKeys("bson", "yaml")}, false, `"u"`},
+		{`$.user_name`, []Option{WithTagKeys("bson", "yaml")}, true, ``},
+		{`$.mail`, []Option{WithTagKeys("bson")}, true, ``},
+		{`$.extra.Region`, []Option{WithTagKeys("mapstructure")}, false, `"eu"`},
+		{`$.username`, nil, true, ``},
+		{`$.username`, []Option{WithCaseInsensitive(true)}, false, `"u"`},
+		{`$.EXTRA.region`, []Option{WithCaseInsensitive(true)}, false, `"eu"`},
+		{`$.store.BOOK[0].Author`, []Option{WithCaseInsensitive(true)}, false, `"Nigel Rees"`},
+	}
+	for _, c := range cases {
+		var doc interface{} = v
+		if c.jsonPath == `$.store.BOOK[0].Author` {
+			doc = data
+		}
+		d, err := MustCompile(c.jsonPath, c.opts...).Get(doc)
+		if err != nil {
+			if !c.hasErr {
+				t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			}
+			continue
+		}
+		if c.hasErr {
+			t.Errorf("Case %q expected error, current:%v", c.jsonPath, d)
+			continue
+		}
+		b, _ := json.Marshal(d)
+		if string(b) != c.expectation {
+			t.Errorf("Case %q, current:%s, expectation:%s", c.jsonPath, b, c.expectation)
+		}
+	}
+	d, err := MustCompile(`$.a`, WithCaseInsensitive(true)).GetString(`{"A":1,"a":2}`)
+	if err != nil || d.(json.Number) != "2" {
+		t.Errorf("exact match should be preferred, current:%v, err:%v", d, err)
+	}
+}
+
+type Tenant struct {
+	Name   string
+	Secret string `jsonpath:"-"`
+	Token  string `json:"token"`
+	Plan   plan
+	notes  []string
+}
+
+type plan struct {
+	tier  string
+	Seats int
+}
+
+func TestFieldVisibility(t *testing.T) {
+	v := Tenant{Name: "n", Secret: "s", Token: "t", Plan: plan{tier: "pro", Seats: 3}, notes: []string{"a"}}
+	noToken := WithFieldPolicy(func(sf reflect.StructField) bool { return sf.Name != "Token" })
+	cases := []struct {
+		jsonPath    string
+		opts        []Option
+		hasErr      bool
+		expectation string
+	}{
+		{`$.Secret`, nil, true, ``},
+		{`$.*`, nil, false, `["n","t",{"Seats":3}]`},
+		{`$..Secret`, nil, false, `[]`},
+		{`$.token`, nil, false, `"t"`},
+		{`$.token`, []Option{noToken}, true, ``},
+		{`$.*`, []Option{noToken}, false, `["n",{"Seats":3}]`},
+		{`$..token`, []Option{noToken}, false, `[]`},
+		{`$.notes`, nil, true, ``},
+		{`$.notes[0]`, []Option{WithUnexportedFields(true)}, false, `"a"`},
+		{`$.Plan.tier`, []Option{WithUnexportedFields(true)}, false, `"pro"`},
+		{`$..tier`, []Option{WithUnexportedFields(true)}, false, `["pro"]`},
+		{`$.Secret`, []Option{WithUnexportedFields(true)}, true, ``},
+	}
+	for _, c := range cases {
+		for _, doc := range []interface{}{v, &v} {
+			d, err := MustCompile(c.jsonPath, c.opts...).Get(doc)
+			if err != nil {
+				if !c.hasErr {
+					t.Errorf("Case %q err: %+v", c.jsonPath, err)
+				}
+				continue
+			}
+			if c.hasErr {
+				t.Errorf("Case %q expected error, current:%v", c.jsonPath, d)
+				continue
+			}
+			b, _ := json.Marshal(d)
+			if string(b) != c.expectation {
+				t.Errorf("Case %q, current:%s, expectation:%s", c.jsonPath, b, c.expectation)
+			}
+		}
+	}
+
+	// Structs returned as a whole are not filtered.
+	for _, path := range []string{`$.user`, `$..user`} {
+		d, err := MustCompile(path, noToken).Get(map[string]interface{}{"user": v})
+		if a, ok := d.([]interface{}); ok && len(a) == 1 {
+			d = a[0]
+		}
+		if u, ok := d.(Tenant); err != nil || !ok || u.Secret != "s" || u.Token != "t" {
+			t.Errorf("Case %q, current:%v, err:%v", path, d, err)
+		}
+	}
+}
+
+func loadBigData(b *testing.B) interface{} {
 	f, err := os.Open("data/big_data.json")
 	if err != nil {
 		b.Fatalf("open file %+v", err)
@@ -113,6 +782,11 @@ func BenchmarkGet(b *testing.B) {
 	if err != nil {
 		b.Fatalf("unmarshal %+v", err)
 	}
+	return data
+}
+
+func BenchmarkGet(b *testing.B) {
+	data := loadBigData(b)
 	for i := 0; i < b.N; i++ {
 		_, err := Get(`$..*`, data)
 		if err != nil {
@@ -120,3 +794,77 @@ func BenchmarkGet(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkCompiledGet(b *testing.B) {
+	data := loadBigData(b)
+	for _, p := range []string{
+		`$..*`,
+		`$..id`,
+		`$.slaves[0].hostname`,
+		`$.slaves[*].['id','pid']`,
+		`$.slaves[:10].resources`,
+		`$.completed_frameworks[*].completed_tasks[*].resources.mem`,
+	} {
+		c := MustCompile(p)
+		b.Run(p, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := c.Get(data)
+				if err != nil {
+					b.Fatalf("%+v\n", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetStruct(b *testing.B) {
+	for _, p := range []string{`$.store.book[*].isbn`, `$..price`} {
+		c := MustCompile(p)
+		b.Run(p, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := c.Get(data)
+				if err != nil {
+					b.Fatalf("%+v\n", err)
+				}
+			}
+		})
+	}
+}
+
+var fuzzSeeds = []string{
+	`$`, `$.*`, `$..*`, `$..`, `$.store.book[*].author`, `$.store.book[*].['author',"price"]`,
+	`$..book[2]`, `$..book[-1:]`, `$..book[0,1]`, `$..book[:2,3]`, `$[5]`, `$[-5]`, `$[::-1]`,
+	`$[3:0:-1]`, `$[::0]`, `$[1:5:3,7,8].a`, `$.['a\'a', "b\"b"]`, `$[1`, `$[1 `, `$['a`, `$[`,
+}
+
+func FuzzCompile(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, jsonPath string) {
+		c, err := Compile(jsonPath)
+		if err != nil {
+			return
+		}
+		_ = c.a.String()
+	})
+}
+
+func FuzzGetBytes(f *testing.F) {
+	docs := []string{`[]`, `{}`, `[1,2,3]`, `{"a":[[],[1],{"b":null}]}`, `"s"`, `null`}
+	for _, s := range fuzzSeeds {
+		for _, d := range docs {
+			f.Add(s, d)
+		}
+	}
+	f.Fuzz(func(t *testing.T, jsonPath string, doc string) {
+		c, err := Compile(jsonPath)
+		if err != nil {
+			return
+		}
+		_, _ = c.GetString(doc)
+		_, _ = MustCompile(jsonPath, WithMapOrder(MapOrderDocument)).GetString(doc)
+	})
+}
@@ -0,0 +1,18 @@
+package jsonpath
+
+import "github.com/xianlianghe0123/jsonpath/internal/ast"
+
+// Limits bounds the work of a single evaluation. A zero field means no limit.
+type Limits = ast.Limits
+
+// LimitKind tells which budget of Limits was exceeded.
+type LimitKind = ast.LimitKind
+
+const (
+	LimitResults = ast.LimitResults
+	LimitDepth   = ast.LimitDepth
+	LimitVisited = ast.LimitVisited
+)
+
+// LimitError is returned by GetContext when a budget of Limits is exceeded.
+type LimitError = ast.LimitError
@@ -0,0 +1,175 @@
+package jsonpath
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Line is the result of evaluating a path against a line of newline-delimited JSON.
+type Line struct {
+	// Number is the 1-based number of the line in the input.
+	Number int
+	// Value is the result of the path, as GetBytes returns it.
+	Value interface{}
+	// Err is the error of the path, such as ErrNotFound, or the decode error of a malformed
+	// line when WithSkipMalformed is set.
+	Err error
+	// Malformed reports whether the line is not valid JSON.
+	Malformed bool
+}
+
+// LineError is returned by EachLine for a malformed line.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("jsonpath: line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+type lineOptions struct {
+	workers       int
+	skipMalformed bool
+}
+
+type LineOption func(*lineOptions)
+
+// WithWorkers evaluates up to n lines in parallel. Lines are still passed to fn in input order.
+func WithWorkers(n int) LineOption {
+	return func(o *lineOptions) {
+		o.workers = n
+	}
+}
+
+// WithSkipMalformed passes malformed lines to fn with Malformed set, instead of stopping with a *LineError.
+func WithSkipMalformed(enable bool) LineOption {
+	return func(o *lineOptions) {
+		o.skipMalformed = enable
+	}
+}
+
+// EachLine evaluates the path against each line of the newline-delimited JSON read from r,
+// and calls fn with the results in input order. Blank lines are skipped. EachLine stops with
+// the error fn returns, the error reading r, or a *LineError for the first malformed line.
+func (c *Compiled) EachLine(r io.Reader, fn func(line Line) error, opts ...LineOption) error {
+	o := lineOptions{workers: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	handle := func(line Line) error {
+		if line.Malformed && !o.skipMalformed {
+			return &LineError{Line: line.Number, Err: line.Err}
+		}
+		return fn(line)
+	}
+	if o.workers == 1 {
+		return readLines(r, func(n int, b []byte) error {
+			return handle(c.evalLine(n, b))
+		})
+	}
+
+	type job struct {
+		n      int
+		b      []byte
+		result chan Line
+	}
+	var (
+		wg      sync.WaitGroup
+		readErr error
+		done    = make(chan struct{})
+		jobs    = make(chan job)
+		pending = make(chan chan Line, o.workers)
+	)
+	for i := 0; i < o.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.result <- c.evalLine(j.n, j.b)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(pending)
+		defer close(jobs)
+		readErr = readLines(r, func(n int, b []byte) error {
+			j := job{n: n, b: b, result: make(chan Line, 1)}
+			select {
+			case pending <- j.result:
+			case <-done:
+				return errStopped
+			}
+			select {
+			case jobs <- j:
+			case <-done:
+				return errStopped
+			}
+			return nil
+		})
+	}()
+
+	var err error
+	for result := range pending {
+		if err = handle(<-result); err != nil {
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	if readErr != errStopped {
+		return readErr
+	}
+	return nil
+}
+
+var errStopped = errors.New("stopped")
+
+func (c *Compiled) evalLine(n int, b []byte) Line {
+	data, env, end, err := decodePrefix(&c.opts, b)
+	if i := skipSpace(b, end); err == nil && i < len(b) {
+		err = syntaxError(b, i, "after top-level value")
+	}
+	if err != nil {
+		return Line{Number: n, Err: err, Malformed: true}
+	}
+	v, err := c.a.Get(env, data)
+	return Line{Number: n, Value: v, Err: err}
+}
+
+// readLines calls fn with the number and content of each non-blank line of r until fn returns an error.
+func readLines(r io.Reader, fn func(n int, b []byte) error) error {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		b, err := br.ReadBytes('\n')
+		if len(b) > 0 {
+			if b = bytes.TrimSpace(b); len(b) > 0 {
+				if e := fn(n, b); e != nil {
+					return e
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
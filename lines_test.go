@@ -0,0 +1,74 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEachLine(t *testing.T) {
+	input := strings.Builder{}
+	expectation := make([]string, 0)
+	for i := 1; i <= 200; i++ {
+		switch {
+		case i%50 == 0:
+			input.WriteString("{\"id\":\n")
+			expectation = append(expectation, fmt.Sprintf("%d malformed", i))
+		case i%45 == 0:
+			input.WriteString(`{"id":2} trailing` + "\n")
+			expectation = append(expectation, fmt.Sprintf("%d malformed", i))
+		case i%30 == 0:
+			input.WriteString("  \r\n")
+		case i%7 == 0:
+			input.WriteString(`{"name":"x"}` + "\n")
+			expectation = append(expectation, fmt.Sprintf("%d not found", i))
+		default:
+			input.WriteString(fmt.Sprintf(`{"id":%d}`+"\r\n", i))
+			expectation = append(expectation, fmt.Sprintf("%d %d", i, i))
+		}
+	}
+	c := MustCompile(`$.id`)
+	for _, workers := range []int{1, 4} {
+		cur := make([]string, 0)
+		err := c.EachLine(strings.NewReader(input.String()), func(line Line) error {
+			switch {
+			case line.Malformed:
+				cur = append(cur, fmt.Sprintf("%d malformed", line.Number))
+			case errors.Is(line.Err, ErrNotFound):
+				cur = append(cur, fmt.Sprintf("%d not found", line.Number))
+			default:
+				cur = append(cur, fmt.Sprintf("%d %v", line.Number, line.Value))
+			}
+			return nil
+		}, WithWorkers(workers), WithSkipMalformed(true))
+		if err != nil {
+			t.Errorf("Case %d workers err: %+v", workers, err)
+			continue
+		}
+		if !reflect.DeepEqual(cur, expectation) {
+			t.Errorf("Case %d workers, current:%v, expectation:%v", workers, cur, expectation)
+		}
+
+		n := 0
+		err = c.EachLine(strings.NewReader(input.String()), func(line Line) error {
+			n = line.Number
+			return nil
+		}, WithWorkers(workers))
+		var lineErr *LineError
+		if !errors.As(err, &lineErr) || lineErr.Line != 45 || n != 44 {
+			t.Errorf("Case %d workers should stop at line 45, current:%v, last:%d", workers, err, n)
+		}
+
+		err = c.EachLine(strings.NewReader(input.String()), func(line Line) error {
+			if line.Number == 10 {
+				return errStop
+			}
+			return nil
+		}, WithWorkers(workers))
+		if !errors.Is(err, errStop) {
+			t.Errorf("Case %d workers fn error should stop, current:%v", workers, err)
+		}
+	}
+}
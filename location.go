@@ -0,0 +1,113 @@
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Location is where a matched value is in the input of GetLocations.
+type Location struct {
+	// Path is the normalized path of the value, such as `$['store']['book'][0]`.
+	Path string
+	// Start and End are the byte offsets of the value, End excluded.
+	Start int
+	End   int
+	// Line and Column are the 1-based position of Start, Column is counted in bytes.
+	Line   int
+	Column int
+}
+
+// GetLocations evaluates the path against the JSON document b like GetRaw,
+// and returns where each matched value is in b.
+func (c *Compiled) GetLocations(b []byte) ([]Location, error) {
+	values, err := c.getRaw(&rawDoc{b: b, paths: true})
+	if err != nil {
+		return nil, err
+	}
+	var lines []int
+	for i, c := range b {
+		if c == '\n' {
+			lines = append(lines, i)
+		}
+	}
+	result := make([]Location, 0, len(values))
+	for _, v := range values {
+		line := sort.SearchInts(lines, v.start)
+		column := v.start + 1
+		if line > 0 {
+			column = v.start - lines[line-1]
+		}
+		result = append(result, Location{
+			Path:   v.path.String(),
+			Start:  v.start,
+			End:    v.end,
+			Line:   line + 1,
+			Column: column,
+		})
+	}
+	return result, nil
+}
+
+// String returns the normalized path of p as defined by RFC 9535.
+func (p *rawPath) String() string {
+	var elems []pathElem
+	for ; p != nil; p = p.parent {
+		elems = append(elems, pathElem{key: p.key, index: p.index})
+	}
+	for i, j := 0, len(elems)-1; i < j; i, j = i+1, j-1 {
+		elems[i], elems[j] = elems[j], elems[i]
+	}
+	return formatPath(elems)
+}
+
+// pathElem is a member name or, if index is not negative, an array index.
+type pathElem struct {
+	key   string
+	index int
+}
+
+func formatPath(elems []pathElem) string {
+	builder := strings.Builder{}
+	builder.WriteRune('$')
+	for _, e := range elems {
+		builder.WriteRune('[')
+		if e.index >= 0 {
+			builder.WriteString(strconv.Itoa(e.index))
+		} else {
+			writeNormalizedName(&builder, e.key)
+		}
+		builder.WriteRune(']')
+	}
+	return builder.String()
+}
+
+func writeNormalizedName(builder *strings.Builder, name string) {
+	builder.WriteRune('\'')
+	for _, r := range name {
+		switch r {
+		case '\'':
+			builder.WriteString(`\'`)
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\b':
+			builder.WriteString(`\b`)
+		case '\f':
+			builder.WriteString(`\f`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				builder.WriteString(fmt.Sprintf(`\u%04x`, r))
+			} else {
+				builder.WriteRune(r)
+			}
+		}
+	}
+	builder.WriteRune('\'')
+}
@@ -0,0 +1,41 @@
+package jsonpath
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetLocations(t *testing.T) {
+	doc := []byte("{\n  \"a\": [1, {\"b'\\n\": true}],\n  \"c\": \"x\"\n}")
+	cases := []struct {
+		jsonPath    string
+		expectation []Location
+	}{
+		{`$`, []Location{{`$`, 0, 42, 1, 1}}},
+		{`$.a[0]`, []Location{{`$['a'][0]`, 10, 11, 2, 9}}},
+		{`$.c`, []Location{{`$['c']`, 37, 40, 3, 8}}},
+		{`$..*`, []Location{
+			{`$['a']`, 9, 28, 2, 8},
+			{`$['c']`, 37, 40, 3, 8},
+			{`$['a'][0]`, 10, 11, 2, 9},
+			{`$['a'][1]`, 13, 27, 2, 12},
+			{`$['a'][1]['b\'\n']`, 22, 26, 2, 21},
+		}},
+	}
+	for _, c := range cases {
+		locs, err := MustCompile(c.jsonPath).GetLocations(doc)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			continue
+		}
+		if !reflect.DeepEqual(locs, c.expectation) {
+			t.Errorf("Case %q, current:%v, expectation:%v", c.jsonPath, locs, c.expectation)
+		}
+		for _, l := range locs {
+			raw, _ := MustCompile(l.Path).GetRaw(doc)
+			if len(raw) != 1 || string(raw[0]) != string(doc[l.Start:l.End]) {
+				t.Errorf("Case %q, path %q selects %s, expectation:%s", c.jsonPath, l.Path, raw, doc[l.Start:l.End])
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package jsonpath
+
+import (
+	"reflect"
+
+	"github.com/xianlianghe0123/jsonpath/internal/ast"
+)
+
+// MapOrder controls the order in which `*` and `..` visit the entries of a map.
+type MapOrder = ast.MapOrder
+
+const (
+	// MapOrderSorted visits map entries sorted by key, the order encoding/json marshals them in.
+	MapOrderSorted = ast.MapOrderSorted
+	// MapOrderRandom visits map entries in reflect.MapRange order.
+	MapOrderRandom = ast.MapOrderRandom
+	// MapOrderDocument visits the maps decoded by GetBytes and GetString in the order their keys
+	// appear in the document. Other maps are visited sorted by key.
+	MapOrderDocument = ast.MapOrderDocument
+)
+
+type Option func(*ast.Options)
+
+func WithMapOrder(order MapOrder) Option {
+	return func(o *ast.Options) {
+		o.MapOrder = order
+	}
+}
+
+// NumberMode controls how GetBytes and GetString decode JSON numbers.
+type NumberMode = ast.NumberMode
+
+const (
+	// NumberJSON decodes numbers as json.Number, keeping their text.
+	NumberJSON = ast.NumberJSON
+	// NumberFloat64 decodes numbers as float64, like encoding/json does by default.
+	NumberFloat64 = ast.NumberFloat64
+	// NumberInt64 decodes integers that fit as int64 and other numbers as float64.
+	NumberInt64 = ast.NumberInt64
+	// NumberBig decodes integers as *big.Int and other numbers as *big.Float.
+	NumberBig = ast.NumberBig
+)
+
+func WithNumbers(mode NumberMode) Option {
+	return func(o *ast.Options) {
+		o.Numbers = mode
+	}
+}
+
+// CyclePolicy controls what `..` does when it reaches a pointer, map or slice that contains itself.
+type CyclePolicy = ast.CyclePolicy
+
+const (
+	// CycleReturnError aborts the evaluation with a *CycleError.
+	CycleReturnError = ast.CycleReturnError
+	// CycleSkip does not descend into the value again.
+	CycleSkip = ast.CycleSkip
+)
+
+// CycleError is returned when `..` reaches a cycle under CycleReturnError.
+type CycleError = ast.CycleError
+
+func WithCyclePolicy(policy CyclePolicy) Option {
+	return func(o *ast.Options) {
+		o.CyclePolicy = policy
+	}
+}
+
+// WithMarshalers makes values implementing json.Marshaler or encoding.TextMarshaler, such as time.Time,
+// behave like their marshaled JSON. A value is only marshaled when the path reaches it.
+func WithMarshalers(enable bool) Option {
+	return func(o *ast.Options) {
+		o.UseMarshalers = enable
+	}
+}
+
+// Unwrapper is implemented by wrapper types, such as optional values, that stand for the value they hold.
+// ok is false if the wrapper holds no valid value, which is then treated as null.
+type Unwrapper = ast.Unwrapper
+
+// WithUnwrap makes values implementing Unwrapper or driver.Valuer, such as sql.NullString,
+// behave like the value they hold. It is applied before WithMarshalers.
+func WithUnwrap(enable bool) Option {
+	return func(o *ast.Options) {
+		o.Unwrap = enable
+	}
+}
+
+// WithTagKeys sets the struct tag keys field names and options are read from, by priority.
+// The default is `json`.
+func WithTagKeys(keys ...string) Option {
+	return func(o *ast.Options) {
+		o.TagKeys = keys
+	}
+}
+
+// WithCaseInsensitive makes member names match struct fields and map keys case-insensitively
+// when there is no exact match, like encoding/json does when unmarshaling.
+func WithCaseInsensitive(enable bool) Option {
+	return func(o *ast.Options) {
+		o.CaseInsensitive = enable
+	}
+}
+
+// WithFieldPolicy hides the struct fields for which allow returns false from member names, `*` and `..`.
+// Fields tagged `jsonpath:"-"` are always hidden. Only the selection of fields is filtered: a struct
+// returned as a whole, such as by `$`, `$.user` or `$..`, still holds its hidden fields.
+func WithFieldPolicy(allow func(sf reflect.StructField) bool) Option {
+	return func(o *ast.Options) {
+		o.FieldPolicy = allow
+	}
+}
+
+// WithUnexportedFields makes unexported struct fields reachable under their Go names.
+// It is meant for debugging tools and should not be used with untrusted paths.
+func WithUnexportedFields(enable bool) Option {
+	return func(o *ast.Options) {
+		o.UnexportedFields = enable
+	}
+}
+
+// WithParallel makes `..` descend into the elements of arrays of at least threshold elements
+// with up to workers goroutines. Results keep the sequential order. Limits are checked by each
+// goroutine and again for their sum once they are done, and the goroutines stop once the
+// context of GetContext is done or one of them fails.
+func WithParallel(workers, threshold int) Option {
+	return func(o *ast.Options) {
+		o.ParallelWorkers = workers
+		o.ParallelThreshold = threshold
+	}
+}
@@ -0,0 +1,256 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/xianlianghe0123/jsonpath/internal/ast"
+)
+
+// rawDoc is the input of a GetRaw call. err records the first syntax error met while scanning it.
+// If paths is set, values record the path they were reached by.
+type rawDoc struct {
+	b     []byte
+	err   error
+	paths bool
+}
+
+// rawValue is the value b[start:end] of a rawDoc as seen by the paths, traversed by rawAdapter.
+// end is negative until the value has been scanned. parent is the start of the object or array
+// holding the value, or negative for the root, and entry the start of its member key or of itself.
+type rawValue struct {
+	d      *rawDoc
+	start  int
+	end    int
+	entry  int
+	parent int
+	path   *rawPath
+}
+
+// rawPath is the member name or, if index is not negative, the array index of a value in its parent.
+type rawPath struct {
+	parent *rawPath
+	key    string
+	index  int
+}
+
+func init() {
+	ast.RegisterBuiltinAdapter(reflect.TypeOf(rawValue{}), rawAdapter{})
+}
+
+// GetRaw evaluates the path against the JSON document b without decoding it, and returns the
+// matched values as sub-slices of b. Only the parts of b the path reaches are scanned, so
+// syntax errors elsewhere are not reported. Duplicate keys select their last value, so all
+// the members of an object a member is looked up in are scanned, and their syntax errors reported.
+func (c *Compiled) GetRaw(b []byte) ([]json.RawMessage, error) {
+	values, err := c.getRaw(&rawDoc{b: b})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]json.RawMessage, 0, len(values))
+	for _, v := range values {
+		result = append(result, json.RawMessage(b[v.start:v.end:v.end]))
+	}
+	return result, nil
+}
+
+// getRaw evaluates the path against doc and returns the matched values with their end scanned.
+func (c *Compiled) getRaw(doc *rawDoc) ([]rawValue, error) {
+	b := doc.b
+	start := skipSpace(b, 0)
+	if start >= len(b) {
+		return nil, syntaxError(b, start, "looking for beginning of value")
+	}
+	d, err := c.Get(rawValue{d: doc, start: start, end: -1, entry: start, parent: -1})
+	if doc.err != nil {
+		return nil, doc.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	var values []rawValue
+	switch v := d.(type) {
+	case rawValue:
+		values = []rawValue{v}
+	case []interface{}:
+		values = make([]rawValue, 0, len(v))
+		for _, e := range v {
+			values = append(values, e.(rawValue))
+		}
+	}
+	for i, v := range values {
+		if v.end < 0 {
+			end, err := skipValue(b, v.start, 0)
+			if err != nil {
+				return nil, err
+			}
+			values[i].end = end
+		}
+	}
+	return values, nil
+}
+
+func (v rawValue) kind() byte {
+	if v.start < len(v.d.b) {
+		return v.d.b[v.start]
+	}
+	return 0
+}
+
+// rangeRaw calls fn with the key literal, which is nil for arrays, and the element
+// of each entry of the object or array v until fn returns false.
+func (v rawValue) rangeRaw(fn func(key []byte, escaped bool, elem rawValue) bool) {
+	b := v.d.b
+	object := v.kind() == '{'
+	closing := byte(']')
+	if object {
+		closing = '}'
+	}
+	i := skipSpace(b, v.start+1)
+	if i < len(b) && b[i] == closing {
+		return
+	}
+	n := 0
+	for {
+		var (
+			key     []byte
+			escaped bool
+			err     error
+		)
+		entry := i
+		if object {
+			if i >= len(b) || b[i] != '"' {
+				v.d.fail(syntaxError(b, i, "looking for beginning of object key string"))
+				return
+			}
+			end := 0
+			if end, escaped, err = scanString(b, i); err != nil {
+				v.d.fail(err)
+				return
+			}
+			key = b[i:end]
+			i = skipSpace(b, end)
+			if i >= len(b) || b[i] != ':' {
+				v.d.fail(syntaxError(b, i, "after object key"))
+				return
+			}
+			i = skipSpace(b, i+1)
+		}
+		end, err := skipValue(b, i, 0)
+		if err != nil {
+			v.d.fail(err)
+			return
+		}
+		elem := rawValue{d: v.d, start: i, end: end, entry: entry, parent: v.start}
+		if v.d.paths {
+			elem.path = &rawPath{parent: v.path, index: n}
+			if object {
+				elem.path.key, _ = unquote(key, escaped)
+				elem.path.index = -1
+			}
+		}
+		if !fn(key, escaped, elem) {
+			return
+		}
+		n++
+		i = skipSpace(b, end)
+		if i < len(b) && b[i] == ',' {
+			i = skipSpace(b, i+1)
+			continue
+		}
+		if i >= len(b) || b[i] != closing {
+			v.d.fail(syntaxError(b, i, "after container element"))
+		}
+		return
+	}
+}
+
+func (d *rawDoc) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+type rawAdapter struct{}
+
+func (rawAdapter) Kind(v interface{}) ast.Kind {
+	switch v.(rawValue).kind() {
+	case '{':
+		return ast.KindObject
+	case '[':
+		return ast.KindArray
+	}
+	return ast.KindScalar
+}
+
+func (rawAdapter) Field(v interface{}, name string) (interface{}, bool) {
+	var (
+		found rawValue
+		ok    bool
+	)
+	v.(rawValue).rangeRaw(func(key []byte, escaped bool, elem rawValue) bool {
+		if escaped {
+			if s, err := unquote(key, true); err == nil && s == name {
+				found, ok = elem, true
+			}
+		} else if string(key[1:len(key)-1]) == name {
+			found, ok = elem, true
+		}
+		return true
+	})
+	return found, ok
+}
+
+func (rawAdapter) Index(v interface{}, i int) (interface{}, bool) {
+	if i < 0 {
+		return nil, false
+	}
+	var (
+		found rawValue
+		ok    bool
+		n     int
+	)
+	v.(rawValue).rangeRaw(func(_ []byte, _ bool, elem rawValue) bool {
+		if n == i {
+			found, ok = elem, true
+			return false
+		}
+		n++
+		return true
+	})
+	return found, ok
+}
+
+func (rawAdapter) Len(v interface{}) int {
+	n := 0
+	v.(rawValue).rangeRaw(func([]byte, bool, rawValue) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func (rawAdapter) Keys(v interface{}) []string {
+	keys := make([]string, 0)
+	v.(rawValue).rangeRaw(func(key []byte, escaped bool, _ rawValue) bool {
+		if s, err := unquote(key, escaped); err == nil {
+			keys = append(keys, s)
+		}
+		return true
+	})
+	return keys
+}
+
+func (rawAdapter) Range(v interface{}, fn func(key interface{}, value interface{}) bool) {
+	rv := v.(rawValue)
+	object := rv.kind() == '{'
+	i := 0
+	rv.rangeRaw(func(key []byte, escaped bool, elem rawValue) bool {
+		if object {
+			s, _ := unquote(key, escaped)
+			return fn(s, elem)
+		}
+		i++
+		return fn(i-1, elem)
+	})
+}
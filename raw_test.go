@@ -0,0 +1,139 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGetRaw(t *testing.T) {
+	b, _ := json.MarshalIndent(data, "", "  ")
+	paths := []string{
+		`$`,
+		`$.*`,
+		`$.store.book[*].author`,
+		`$.store.book[*].['author',"price"]`,
+		`$..author`,
+		`$..price`,
+		`$..*`,
+		`$.store.book[-1]`,
+		`$.store.book[1:3]`,
+		`$.store.book[::-2].title`,
+		`$.store.bicycle['color','size']`,
+	}
+	for _, p := range paths {
+		c := MustCompile(p, WithMapOrder(MapOrderDocument))
+		e, err := c.GetBytes(b)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", p, err)
+			continue
+		}
+		raw, err := c.GetRaw(b)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", p, err)
+			continue
+		}
+		d := make([]interface{}, 0, len(raw))
+		for _, m := range raw {
+			dec := json.NewDecoder(bytes.NewReader(m))
+			dec.UseNumber()
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				t.Errorf("Case %q decode %s err: %+v", p, m, err)
+			}
+			d = append(d, v)
+		}
+		if _, ok := e.([]interface{}); !ok {
+			e = []interface{}{e}
+		}
+		if !reflect.DeepEqual(d, e) {
+			t.Errorf("Case %q, current:%v, expectation:%v", p, d, e)
+		}
+	}
+
+	doc := []byte(`{"ab":[1, {"c": "x"}], "d": 1, "d": [2]}`)
+	cases := []struct {
+		jsonPath    string
+		hasErr      bool
+		expectation string
+	}{
+		{`$.ab[1]`, false, `[{"c": "x"}]`},
+		{`$.ab[1].c`, false, `["x"]`},
+		{`$.ab[3]`, true, ``},
+		{`$.d`, false, `[[2]]`},
+		{`$.d[0]`, false, `[2]`},
+		{`$.e`, true, ``},
+	}
+	for _, c := range cases {
+		raw, err := MustCompile(c.jsonPath).GetRaw(doc)
+		if err != nil {
+			if !c.hasErr {
+				t.Errorf("Case %q err: %+v", c.jsonPath, err)
+			}
+			continue
+		}
+		if c.hasErr {
+			t.Errorf("Case %q expected error, current:%s", c.jsonPath, raw)
+			continue
+		}
+		cur, _ := json.Marshal(raw)
+		var e interface{}
+		json.Unmarshal([]byte(c.expectation), &e)
+		if exp, _ := json.Marshal(e); !bytes.Equal(cur, exp) {
+			t.Errorf("Case %q, current:%s, expectation:%s", c.jsonPath, cur, exp)
+		}
+		if len(raw) > 0 && !bytes.Contains(doc, raw[0]) {
+			t.Errorf("Case %q should return a sub-slice of the input", c.jsonPath)
+		}
+	}
+	bad := []byte(`[{"c":"x"}, [1 2]]`)
+	if raw, err := MustCompile(`$[0].c`).GetRaw(bad); err != nil || string(raw[0]) != `"x"` {
+		t.Errorf("syntax errors after the match should not be reported, current:%s, err:%v", raw, err)
+	}
+	if _, err := MustCompile(`$[1].*`).GetRaw(bad); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("syntax errors in scanned values should be reported, current:%v", err)
+	}
+	bad = []byte(`[{"c":"y"}, {"c":"x", "d":[1 2]}, "c":{]`)
+	if _, err := MustCompile(`$[1].c`).GetRaw(bad); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("syntax errors in the siblings of a looked up member should be reported, current:%v", err)
+	}
+	if raw, err := MustCompile(`$[0].c`).GetRaw(bad); err != nil || string(raw[0]) != `"y"` {
+		t.Errorf("syntax errors in other elements should not be reported, current:%s, err:%v", raw, err)
+	}
+	if _, err := MustCompile(`$`).GetRaw([]byte(`  `)); err == nil {
+		t.Errorf("empty input expected error")
+	}
+}
+
+func BenchmarkGetRaw(b *testing.B) {
+	d, _ := json.Marshal(loadBigData(b))
+	c := MustCompile(`$.slaves[0].hostname`)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.GetRaw(d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetRawSlice(b *testing.B) {
+	a := make([]int, 8000)
+	for i := range a {
+		a[i] = i
+	}
+	d, _ := json.Marshal(a)
+	for _, path := range []string{`$[*]`, `$[0:]`, `$[-100:]`, `$[10:20]`} {
+		c := MustCompile(path)
+		b.Run(path, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.GetRaw(d); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// maxNestingDepth is the nesting depth of objects and arrays the scanners accept, the same as encoding/json.
+const maxNestingDepth = 10000
+
+func syntaxError(b []byte, i int, what string) error {
+	if i >= len(b) {
+		return fmt.Errorf("unexpected end of JSON input")
+	}
+	return fmt.Errorf("invalid character %q %s at offset %d", b[i], what, i)
+}
+
+func skipSpace(b []byte, i int) int {
+	for i < len(b) {
+		switch b[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanString scans the string starting at the quote b[i] and returns the offset after its closing quote.
+// escaped reports whether the string contains escape sequences.
+func scanString(b []byte, i int) (end int, escaped bool, err error) {
+	for i++; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == '"':
+			return i + 1, escaped, nil
+		case c == '\\':
+			escaped = true
+			i++
+			if i >= len(b) {
+				return 0, false, syntaxError(b, i, "in string escape code")
+			}
+			switch b[i] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			case 'u':
+				for j := 0; j < 4; j++ {
+					i++
+					if i >= len(b) || !isHex(b[i]) {
+						return 0, false, syntaxError(b, i, "in \\u hexadecimal character escape")
+					}
+				}
+			default:
+				return 0, false, syntaxError(b, i, "in string escape code")
+			}
+		case c < 0x20:
+			return 0, false, syntaxError(b, i, "in string literal")
+		}
+	}
+	return 0, false, syntaxError(b, i, "in string literal")
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// scanNumber scans the number starting at b[i] and returns the offset after it.
+func scanNumber(b []byte, i int) (int, error) {
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(b) && b[i] == '0':
+		i++
+	case i < len(b) && isDigit(b[i]):
+		for i < len(b) && isDigit(b[i]) {
+			i++
+		}
+	default:
+		return 0, syntaxError(b, i, "in numeric literal")
+	}
+	if i < len(b) && b[i] == '.' {
+		i++
+		if i >= len(b) || !isDigit(b[i]) {
+			return 0, syntaxError(b, i, "after decimal point in numeric literal")
+		}
+		for i < len(b) && isDigit(b[i]) {
+			i++
+		}
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		if i >= len(b) || !isDigit(b[i]) {
+			return 0, syntaxError(b, i, "in exponent of numeric literal")
+		}
+		for i < len(b) && isDigit(b[i]) {
+			i++
+		}
+	}
+	return i, nil
+}
+
+func scanLiteral(b []byte, i int, lit string) (int, error) {
+	for j := 0; j < len(lit); j++ {
+		if i+j >= len(b) || b[i+j] != lit[j] {
+			return 0, syntaxError(b, i+j, "in literal "+lit)
+		}
+	}
+	return i + len(lit), nil
+}
+
+// skipValue scans the value starting at b[i], which must not be space, and returns the offset after it.
+func skipValue(b []byte, i int, depth int) (int, error) {
+	if i >= len(b) {
+		return 0, syntaxError(b, i, "looking for beginning of value")
+	}
+	switch c := b[i]; {
+	case c == '"':
+		end, _, err := scanString(b, i)
+		return end, err
+	case c == '{', c == '[':
+		if depth >= maxNestingDepth {
+			return 0, fmt.Errorf("exceeded max depth at offset %d", i)
+		}
+		return skipContainer(b, i, depth+1)
+	case c == '-' || isDigit(c):
+		return scanNumber(b, i)
+	case c == 't':
+		return scanLiteral(b, i, "true")
+	case c == 'f':
+		return scanLiteral(b, i, "false")
+	case c == 'n':
+		return scanLiteral(b, i, "null")
+	}
+	return 0, syntaxError(b, i, "looking for beginning of value")
+}
+
+func skipContainer(b []byte, i int, depth int) (int, error) {
+	object := b[i] == '{'
+	closing := byte(']')
+	if object {
+		closing = '}'
+	}
+	i = skipSpace(b, i+1)
+	if i < len(b) && b[i] == closing {
+		return i + 1, nil
+	}
+	for {
+		var err error
+		if object {
+			if i >= len(b) || b[i] != '"' {
+				return 0, syntaxError(b, i, "looking for beginning of object key string")
+			}
+			if i, _, err = scanString(b, i); err != nil {
+				return 0, err
+			}
+			i = skipSpace(b, i)
+			if i >= len(b) || b[i] != ':' {
+				return 0, syntaxError(b, i, "after object key")
+			}
+			i = skipSpace(b, i+1)
+		}
+		if i, err = skipValue(b, i, depth); err != nil {
+			return 0, err
+		}
+		i = skipSpace(b, i)
+		if i < len(b) && b[i] == ',' {
+			i = skipSpace(b, i+1)
+			continue
+		}
+		if i < len(b) && b[i] == closing {
+			return i + 1, nil
+		}
+		return 0, syntaxError(b, i, "after container element")
+	}
+}
+
+// unquote returns the value of the string literal s, which has been checked by scanString.
+func unquote(s []byte, escaped bool) (string, error) {
+	if !escaped {
+		return string(s[1 : len(s)-1]), nil
+	}
+	var str string
+	if err := json.Unmarshal(s, &str); err != nil {
+		return "", err
+	}
+	return str, nil
+}
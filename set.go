@@ -0,0 +1,155 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xianlianghe0123/jsonpath/internal/ast"
+)
+
+// CompiledSet is a set of named paths evaluated together. The member and index steps the
+// paths start with are merged into a prefix tree, so a value shared by several paths is
+// looked up once.
+type CompiledSet struct {
+	root *prefixNode
+	opts ast.Options
+}
+
+// prefixNode is reached from its parent by step. rests are the paths continuing from it,
+// by name.
+type prefixNode struct {
+	step     *ast.AST
+	children []*prefixNode
+	byStep   map[string]*prefixNode
+	names    []string
+	rests    []*ast.AST
+}
+
+// CompileSet compiles the paths, keyed by the names their results are returned under.
+func CompileSet(paths map[string]string, opts ...Option) (*CompiledSet, error) {
+	s := &CompiledSet{
+		root: &prefixNode{byStep: make(map[string]*prefixNode)},
+	}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c, err := Compile(paths[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		steps, rest := c.a.Prefix()
+		n := s.root
+		for _, step := range steps {
+			key := step.String()
+			child, ok := n.byStep[key]
+			if !ok {
+				child = &prefixNode{step: step, byStep: make(map[string]*prefixNode)}
+				n.byStep[key] = child
+				n.children = append(n.children, child)
+			}
+			n = child
+		}
+		n.names = append(n.names, name)
+		n.rests = append(n.rests, rest)
+	}
+	return s, nil
+}
+
+// SetError is returned along with the results of the other paths when some paths of a CompiledSet
+// fail with an error other than ErrNotFound. Errs holds the errors by path name.
+type SetError struct {
+	Errs map[string]error
+}
+
+func (e *SetError) Error() string {
+	names := make([]string, 0, len(e.Errs))
+	for name := range e.Errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", name, e.Errs[name]))
+	}
+	return fmt.Sprintf("jsonpath: %d paths failed: %s", len(names), strings.Join(msgs, "; "))
+}
+
+// Get evaluates the paths against data, and returns their results by name.
+// The paths selecting nothing are left out. Each path is evaluated on its own, so that
+// if some fail, the results of the others are returned with a *SetError.
+func (s *CompiledSet) Get(data interface{}) (map[string]interface{}, error) {
+	return s.get(ast.NewEnv(&s.opts), data)
+}
+
+// GetBytes is like Get, but decodes dataBytes once for all the paths. It fails as a whole
+// if dataBytes can not be decoded.
+func (s *CompiledSet) GetBytes(dataBytes []byte) (map[string]interface{}, error) {
+	data, env, err := decode(&s.opts, dataBytes)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(env, data)
+}
+
+func (s *CompiledSet) get(env *ast.Env, data interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	errs := make(map[string]error)
+	s.root.get(env, data, result, errs)
+	if len(errs) > 0 {
+		return result, &SetError{Errs: errs}
+	}
+	return result, nil
+}
+
+// get evaluates the paths from n against data, and records their results and errors by name.
+// A path aborting env, like with a *CycleError, does not abort the paths after it.
+func (n *prefixNode) get(env *ast.Env, data interface{}, result map[string]interface{}, errs map[string]error) {
+	for i, rest := range n.rests {
+		env = recovered(env)
+		v, err := rest.Get(env, data)
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				errs[n.names[i]] = err
+			}
+			continue
+		}
+		result[n.names[i]] = v
+	}
+	for _, child := range n.children {
+		env = recovered(env)
+		v, err := child.step.Get(env, data)
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				child.fail(err, errs)
+			}
+			continue
+		}
+		child.get(env, v, result, errs)
+	}
+}
+
+// fail records err for all the paths from n.
+func (n *prefixNode) fail(err error, errs map[string]error) {
+	for _, name := range n.names {
+		errs[name] = err
+	}
+	for _, child := range n.children {
+		child.fail(err, errs)
+	}
+}
+
+// recovered returns env, or a clone of it without its error if it is aborted.
+func recovered(env *ast.Env) *ast.Env {
+	if env.Err() != nil {
+		return env.Clone()
+	}
+	return env
+}
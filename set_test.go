@@ -0,0 +1,103 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCompileSet(t *testing.T) {
+	paths := map[string]string{
+		"first":   `$.store.book[0].author`,
+		"last":    `$.store.book[-1].title`,
+		"authors": `$.store.book[*].author`,
+		"color":   `$.store.bicycle.color`,
+		"prices":  `$..price`,
+		"store":   `$.store`,
+		"root":    `$`,
+		"missing": `$.store.book[9].author`,
+		"none":    `$.store.car`,
+	}
+	s, err := CompileSet(paths)
+	if err != nil {
+		t.Fatalf("compile err: %+v", err)
+	}
+	b, _ := json.Marshal(data)
+	for _, useBytes := range []bool{false, true} {
+		var cur map[string]interface{}
+		if useBytes {
+			cur, err = s.GetBytes(b)
+		} else {
+			cur, err = s.Get(data)
+		}
+		if err != nil {
+			t.Errorf("Case bytes %v err: %+v", useBytes, err)
+			continue
+		}
+		expectation := make(map[string]interface{})
+		for name, p := range paths {
+			var v interface{}
+			if useBytes {
+				v, err = GetBytes(p, b)
+			} else {
+				v, err = Get(p, data)
+			}
+			if err == nil {
+				expectation[name] = v
+			}
+		}
+		if !reflect.DeepEqual(cur, expectation) {
+			t.Errorf("Case bytes %v, current:%v, expectation:%v", useBytes, cur, expectation)
+		}
+	}
+	loop := map[string]interface{}{"a": 1}
+	loop["self"] = loop
+	s, _ = CompileSet(map[string]string{"cycle": `$..x`, "deep": `$.loop.self.a`, "b": `$.b`})
+	cur, err := s.Get(map[string]interface{}{"b": 2, "loop": loop})
+	var setErr *SetError
+	if !errors.As(err, &setErr) || len(setErr.Errs) != 1 || !errors.As(setErr.Errs["cycle"], new(*CycleError)) {
+		t.Errorf("failing path expected a SetError, current:%v", err)
+	}
+	if !reflect.DeepEqual(cur, map[string]interface{}{"b": 2, "deep": 1}) {
+		t.Errorf("other paths should keep their results, current:%v", cur)
+	}
+	if _, err := CompileSet(map[string]string{"bad": `$[`}); err == nil {
+		t.Errorf("invalid path expected error")
+	}
+}
+
+func BenchmarkCompiledSet(b *testing.B) {
+	d := loadBigData(b)
+	paths := map[string]string{
+		"hostname": `$.slaves[0].hostname`,
+		"id":       `$.slaves[0].id`,
+		"pid":      `$.slaves[0].pid`,
+		"cpus":     `$.slaves[0].resources.cpus`,
+		"mem":      `$.slaves[0].resources.mem`,
+		"disk":     `$.slaves[0].resources.disk`,
+	}
+	b.Run("set", func(b *testing.B) {
+		s, _ := CompileSet(paths)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := s.Get(d); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("separate", func(b *testing.B) {
+		cs := make(map[string]*Compiled)
+		for name, p := range paths {
+			cs[name] = MustCompile(p)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, c := range cs {
+				if _, err := c.Get(d); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/xianlianghe0123/jsonpath/internal/ast"
+)
+
+// Stream evaluates the path against the JSON document read from r, and calls fn with the
+// normalized path and the value of each match, in document order. The document is read token
+// by token and only the matched values, and the arrays indexed from their end, are decoded.
+// Numbers are decoded as set by WithNumbers. Stream stops with the error fn returns.
+func (c *Compiled) Stream(r io.Reader, fn func(path string, v interface{}) error) error {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	s := &streamer{
+		c:         c,
+		d:         d,
+		selectors: c.a.Selectors(),
+		fn:        fn,
+	}
+	return s.value([]int{0})
+}
+
+// streamer runs the selectors of a path as a nondeterministic automaton over the values of a
+// document. A state p means the selectors from p on are applied to the value, and the value
+// is matched if p is the number of selectors, or if p is a Containers selector and the value
+// is an object or array.
+type streamer struct {
+	c         *Compiled
+	d         *json.Decoder
+	selectors []ast.Selector
+	path      []pathElem
+	fn        func(path string, v interface{}) error
+}
+
+func (s *streamer) value(states []int) error {
+	if len(states) == 0 {
+		return s.skip()
+	}
+	for _, p := range states {
+		if p == len(s.selectors) || s.selectors[p].Containers {
+			od := &orderedDecoder{d: s.d, keyOrder: make(map[uintptr][]string)}
+			v, err := od.decode()
+			if err != nil {
+				return err
+			}
+			return s.walk(v, states, od.keyOrder)
+		}
+	}
+	t, err := s.d.Token()
+	if err != nil {
+		return err
+	}
+	switch t {
+	case json.Delim('{'):
+		return s.object(states)
+	case json.Delim('['):
+		for _, p := range states {
+			if s.selectors[p].NeedsLen() {
+				return s.decodedArray(states)
+			}
+		}
+		return s.array(states)
+	}
+	return nil
+}
+
+func (s *streamer) object(states []int) error {
+	for s.d.More() {
+		t, err := s.d.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("invalid object key %v", t)
+		}
+		s.path = append(s.path, pathElem{key: key, index: -1})
+		err = s.value(s.next(states, key, -1, -1))
+		s.path = s.path[:len(s.path)-1]
+		if err != nil {
+			return err
+		}
+	}
+	_, err := s.d.Token()
+	return err
+}
+
+func (s *streamer) array(states []int) error {
+	for i := 0; s.d.More(); i++ {
+		s.path = append(s.path, pathElem{index: i})
+		err := s.value(s.next(states, "", i, -1))
+		s.path = s.path[:len(s.path)-1]
+		if err != nil {
+			return err
+		}
+	}
+	_, err := s.d.Token()
+	return err
+}
+
+// decodedArray decodes the rest of an array whose '[' has been read, and walks it.
+func (s *streamer) decodedArray(states []int) error {
+	od := &orderedDecoder{d: s.d, keyOrder: make(map[uintptr][]string)}
+	a, err := od.decodeArray()
+	if err != nil {
+		return err
+	}
+	return s.walk(a, states, od.keyOrder)
+}
+
+// skip reads the next value without decoding it.
+func (s *streamer) skip() error {
+	depth := 0
+	for {
+		t, err := s.d.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// walk runs the automaton over a decoded value.
+func (s *streamer) walk(v interface{}, states []int, keyOrder map[uintptr][]string) error {
+	for _, p := range states {
+		if !s.matches(p, v) {
+			continue
+		}
+		n, err := convertNumbers(v, s.c.opts.Numbers)
+		if err != nil {
+			return err
+		}
+		if err := s.fn(formatPath(s.path), n); err != nil {
+			return err
+		}
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, key := range keyOrder[reflect.ValueOf(t).Pointer()] {
+			next := s.next(states, key, -1, -1)
+			if len(next) == 0 {
+				continue
+			}
+			s.path = append(s.path, pathElem{key: key, index: -1})
+			err := s.walk(t[key], next, keyOrder)
+			s.path = s.path[:len(s.path)-1]
+			if err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, e := range t {
+			next := s.next(states, "", i, len(t))
+			if len(next) == 0 {
+				continue
+			}
+			s.path = append(s.path, pathElem{index: i})
+			err := s.walk(e, next, keyOrder)
+			s.path = s.path[:len(s.path)-1]
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether the value v in the state p is matched.
+func (s *streamer) matches(p int, v interface{}) bool {
+	if p == len(s.selectors) {
+		return true
+	}
+	if !s.selectors[p].Containers {
+		return false
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// next returns the states of the member key, or of the element i of an array of length n if i
+// is not negative, of a value in states. n is negative if it is not known.
+func (s *streamer) next(states []int, key string, i, n int) []int {
+	var next []int
+	for _, p := range states {
+		if p == len(s.selectors) {
+			continue
+		}
+		sel := &s.selectors[p]
+		if sel.Descendant {
+			next = append(next, p)
+		}
+		if i < 0 && sel.MatchName(key) || i >= 0 && sel.MatchIndex(i, n) {
+			next = append(next, p+1)
+		}
+	}
+	return next
+}
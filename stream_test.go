@@ -0,0 +1,103 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	b, _ := json.MarshalIndent(data, "", "  ")
+	paths := []string{
+		`$`,
+		`$.*`,
+		`$.store.book[*].author`,
+		`$.store.book[*].['author',"price"]`,
+		`$..author`,
+		`$..price`,
+		`$..*`,
+		`$.store.book[-1]`,
+		`$.store.book[1:3]`,
+		`$.store.book[::-2].title`,
+		`$.store.book[0,2].isbn`,
+		`$..book[1:].title`,
+		`$.store.bicycle['color','size']`,
+		`$..`,
+		`$.store..`,
+		`$.store.book[0]..`,
+		`$.store.bicycle.color..`,
+	}
+	for _, p := range paths {
+		c := MustCompile(p)
+		locs, err := c.GetLocations(b)
+		if err != nil {
+			t.Errorf("Case %q err: %+v", p, err)
+			continue
+		}
+		expectation := make([]string, 0)
+		for _, l := range locs {
+			var v interface{}
+			json.Unmarshal(b[l.Start:l.End], &v)
+			e, _ := json.Marshal(v)
+			expectation = append(expectation, l.Path+" "+string(e))
+		}
+		cur := make([]string, 0)
+		err = c.Stream(bytes.NewReader(b), func(path string, v interface{}) error {
+			e, _ := json.Marshal(v)
+			cur = append(cur, path+" "+string(e))
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Case %q err: %+v", p, err)
+			continue
+		}
+		sort.Strings(expectation)
+		sort.Strings(cur)
+		if !reflect.DeepEqual(cur, expectation) {
+			t.Errorf("Case %q, current:%v, expectation:%v", p, cur, expectation)
+		}
+		if strings.HasSuffix(p, "..") {
+			values := make([]string, 0)
+			if d, err := c.GetBytes(b); err == nil {
+				for _, v := range d.([]interface{}) {
+					e, _ := json.Marshal(v)
+					values = append(values, string(e))
+				}
+			}
+			for i, e := range cur {
+				cur[i] = e[strings.IndexByte(e, ' ')+1:]
+			}
+			sort.Strings(values)
+			sort.Strings(cur)
+			if !reflect.DeepEqual(cur, values) {
+				t.Errorf("Case %q, current:%v, Get:%v", p, cur, values)
+			}
+		}
+	}
+
+	doc := `[{"id":1,"v":[1,2]},{"id":2,"v":{"z":1,"a":2}},{"id":3}]`
+	cur := make([]string, 0)
+	err := MustCompile(`$[*]..*`, WithNumbers(NumberInt64)).Stream(strings.NewReader(doc), func(path string, v interface{}) error {
+		cur = append(cur, path)
+		if n, ok := v.(int64); ok && n == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("fn error should stop the stream, current:%v", err)
+	}
+	expectation := []string{`$[0]['id']`, `$[0]['v']`, `$[0]['v'][0]`, `$[0]['v'][1]`, `$[1]['id']`, `$[1]['v']`, `$[1]['v']['z']`, `$[1]['v']['a']`, `$[2]['id']`}
+	if !reflect.DeepEqual(cur, expectation) {
+		t.Errorf("current:%v, expectation:%v", cur, expectation)
+	}
+	if err := MustCompile(`$[1]`).Stream(strings.NewReader(`[1,2`), func(string, interface{}) error { return nil }); err == nil {
+		t.Errorf("truncated document expected error")
+	}
+}
+
+var errStop = errors.New("stop")